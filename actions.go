@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/burik666/yagostatus/internal/config"
+	"github.com/burik666/yagostatus/internal/registry"
+	"github.com/burik666/yagostatus/pkg/signals"
+	"github.com/burik666/yagostatus/ygs"
+
+	"go.i3wm.org/i3/v4"
+	"gopkg.in/yaml.v2"
+)
+
+// actionEvent is the event that triggered an action.
+type actionEvent struct {
+	// wi is the widget index, bi is the index of the clicked block in the widget output.
+	wi    int
+	bi    int
+	block ygs.I3BarBlock
+	event ygs.I3BarClickEvent
+}
+
+// builtinAction is an event action implemented by yagostatus.
+type builtinAction struct {
+	// validate checks the action arguments.
+	validate func(args string) error
+	run      func(status *YaGoStatus, e actionEvent, args string) error
+}
+
+var builtinActions = map[string]builtinAction{
+	"refresh": {
+		validate: noArgs,
+		run: func(status *YaGoStatus, e actionEvent, _ string) error {
+			return status.refreshWidget(e.wi)
+		},
+	},
+	"refresh-widget": {
+		validate: func(args string) error {
+			if args == "" {
+				return errors.New("missing widget id")
+			}
+
+			return nil
+		},
+		run: func(status *YaGoStatus, _ actionEvent, args string) error {
+			wi, ok := status.ids[args]
+			if !ok {
+				return fmt.Errorf("widget '%s' not found", args)
+			}
+
+			return status.refreshWidget(wi)
+		},
+	},
+	"stop": {
+		validate: noArgs,
+		run: func(status *YaGoStatus, e actionEvent, _ string) error {
+			return status.widgets[e.wi].instance.Stop()
+		},
+	},
+	"continue": {
+		validate: noArgs,
+		run: func(status *YaGoStatus, e actionEvent, _ string) error {
+			return status.widgets[e.wi].instance.Continue()
+		},
+	},
+	"set-fields": {
+		validate: func(args string) error {
+			_, err := parseFields(args)
+
+			return err
+		},
+		run: func(status *YaGoStatus, e actionEvent, args string) error {
+			fields, _ := parseFields(args)
+
+			return status.setFields(e, fields)
+		},
+	},
+	"i3": {
+		validate: func(args string) error {
+			if args == "" {
+				return errors.New("missing i3 command")
+			}
+
+			return nil
+		},
+		run: func(_ *YaGoStatus, _ actionEvent, args string) error {
+			res, err := i3.RunCommand(args)
+			if err != nil {
+				return err
+			}
+
+			for _, r := range res {
+				if !r.Success {
+					return fmt.Errorf("i3: %s", r.Error)
+				}
+			}
+
+			return nil
+		},
+	},
+	"signal": {
+		validate: func(args string) error {
+			if _, err := parseSignal(args); err != nil && config.ValidateID(args) != nil {
+				return fmt.Errorf("expected a signal number or a widget id: %w", err)
+			}
+
+			return nil
+		},
+		run: func(status *YaGoStatus, _ actionEvent, args string) error {
+			sig, err := parseSignal(args)
+			if err != nil {
+				if sig, err = status.widgetSignal(args); err != nil {
+					return err
+				}
+			}
+
+			return syscall.Kill(os.Getpid(), sig)
+		},
+	},
+}
+
+// splitAction splits the action into the name and the arguments.
+func splitAction(action string) (string, string) {
+	parts := strings.SplitN(strings.TrimSpace(action), " ", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+
+	return parts[0], strings.TrimSpace(parts[1])
+}
+
+// validateAction checks that the action exists and its arguments are valid.
+func validateAction(action string) error {
+	name, args := splitAction(action)
+
+	if a, ok := builtinActions[name]; ok {
+		if err := a.validate(args); err != nil {
+			return fmt.Errorf("action '%s': %w", name, err)
+		}
+
+		return nil
+	}
+
+	if _, ok := registry.Action(name); !ok {
+		return fmt.Errorf("action '%s' not found", name)
+	}
+
+	return nil
+}
+
+// runAction executes a builtin or plugin action.
+func (status *YaGoStatus) runAction(e actionEvent, action string) error {
+	if err := validateAction(action); err != nil {
+		return err
+	}
+
+	name, args := splitAction(action)
+
+	if a, ok := builtinActions[name]; ok {
+		return a.run(status, e, args)
+	}
+
+	a, _ := registry.Action(name)
+	wcfg := status.widgets[e.wi].config
+
+	return a.Func(ygs.ActionContext{
+		Args: args,
+		Widget: ygs.WidgetInfo{
+			ID:    wcfg.ID,
+			Name:  wcfg.Name,
+			File:  wcfg.File,
+			Index: wcfg.Index,
+		},
+		Event: e.event,
+		Block: e.block,
+	}, status.widgets[e.wi].logger.WithPrefix(fmt.Sprintf("[action %s]", name)))
+}
+
+func (status *YaGoStatus) refreshWidget(wi int) error {
+	r, ok := status.widgets[wi].instance.(ygs.Refresher)
+	if !ok {
+		return fmt.Errorf("widget '%s' does not support refresh", status.widgets[wi].config.Name)
+	}
+
+	return r.Refresh()
+}
+
+// setFields patches the fields of the clicked block and updates the widget output.
+func (status *YaGoStatus) setFields(e actionEvent, fields map[string]json.RawMessage) error {
+	status.widgets[e.wi].m.RLock()
+	blocks := make([]ygs.I3BarBlock, len(status.widgets[e.wi].raw))
+	copy(blocks, status.widgets[e.wi].raw)
+	status.widgets[e.wi].m.RUnlock()
+
+	// the output index differs from the index of the widget block if filters dropped blocks
+	bi := -1
+
+	for i := range blocks {
+		if blocks[i].Name == e.block.Name && blocks[i].Instance == e.block.Instance {
+			if bi < 0 || i == e.bi {
+				bi = i
+			}
+		}
+	}
+
+	if bi < 0 && e.bi < len(blocks) {
+		bi = e.bi
+	}
+
+	if bi < 0 {
+		return errors.New("block not found")
+	}
+
+	b, err := json.Marshal(blocks[bi])
+	if err != nil {
+		return err
+	}
+
+	var bm map[string]json.RawMessage
+	if err := json.Unmarshal(b, &bm); err != nil {
+		return err
+	}
+
+	for k, v := range fields {
+		if string(v) == "null" {
+			delete(bm, k)
+
+			continue
+		}
+
+		bm[k] = v
+	}
+
+	b, err = json.Marshal(bm)
+	if err != nil {
+		return err
+	}
+
+	var block ygs.I3BarBlock
+	if err := json.Unmarshal(b, &block); err != nil {
+		return err
+	}
+
+	blocks[bi] = block
+
+	status.widgets[e.wi].ch <- blocks
+
+	return nil
+}
+
+func noArgs(args string) error {
+	if args != "" {
+		return fmt.Errorf("unexpected arguments '%s'", args)
+	}
+
+	return nil
+}
+
+// parseFields parses the fields object, it can be written in JSON or YAML.
+func parseFields(args string) (map[string]json.RawMessage, error) {
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(args), &v); err != nil {
+		return nil, fmt.Errorf("invalid fields: %w", err)
+	}
+
+	b, err := ygs.BlocksJSONFromYAML(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fields: %w", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil || len(fields) == 0 {
+		return nil, fmt.Errorf("invalid fields '%s' (expected an object)", args)
+	}
+
+	return fields, nil
+}
+
+// widgetSignal returns the signal of the widget with the id.
+func (status *YaGoStatus) widgetSignal(id string) (syscall.Signal, error) {
+	wi, ok := status.ids[id]
+	if !ok {
+		return 0, fmt.Errorf("widget '%s' not found", id)
+	}
+
+	v, ok := status.widgets[wi].config.Params["signal"]
+	if !ok {
+		return 0, fmt.Errorf("widget '%s' has no signal", id)
+	}
+
+	return parseSignal(fmt.Sprint(v))
+}
+
+func parseSignal(args string) (syscall.Signal, error) {
+	n, err := strconv.Atoi(args)
+	if err != nil || n < 0 || signals.SIGRTMIN+n > signals.SIGRTMAX {
+		return 0, fmt.Errorf("signal should be between 0 AND %d", signals.SIGRTMAX-signals.SIGRTMIN)
+	}
+
+	return syscall.Signal(signals.SIGRTMIN + n), nil
+}
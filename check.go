@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/burik666/yagostatus/internal/config"
+	"github.com/burik666/yagostatus/internal/registry"
+	"github.com/burik666/yagostatus/ygs"
+)
+
+var (
+	fieldErrRe  = regexp.MustCompile(`field (\S+) not found`)
+	eventErrRe  = regexp.MustCompile(`^events#(\d+): `)
+	filterErrRe = regexp.MustCompile(`^filters#(\d+): `)
+)
+
+type configChecker struct {
+	cfg       *config.Config
+	positions map[string][]config.WidgetPosition
+	problems  int
+}
+
+// checkConfig validates the config file without running widgets,
+// prints all problems as file:line: message and returns the exit code.
+func checkConfig(configFile string, logger ygs.Logger) int {
+	path, err := configPath(configFile)
+	if err == nil {
+		configFile = path
+	}
+
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		c := &configChecker{cfg: &config.Config{File: configFile}}
+		c.reportErr(configFile, 0, err)
+
+		return 1
+	}
+
+	c := &configChecker{
+		cfg:       cfg,
+		positions: make(map[string][]config.WidgetPosition),
+	}
+
+	if err := config.LoadPlugins(*cfg, logger); err != nil {
+		c.report(cfg.File, 0, fmt.Sprintf("plugins: %s", err))
+	}
+
+	if err := config.InitPlugins(logger); err != nil {
+		c.report(cfg.File, 0, fmt.Sprintf("plugins: %s", err))
+	}
+
+	defer config.ShutdownPlugins(logger)
+
+	for _, wcfg := range cfg.Widgets {
+		if wcfg.Err != nil {
+			c.reportWidget(wcfg, wcfg.Err)
+
+			continue
+		}
+
+		if _, _, err := registry.DecodeParams(wcfg); err != nil {
+			c.reportWidget(wcfg, err)
+		}
+
+		for ei := range wcfg.Events {
+			if wcfg.Events[ei].Action == "" {
+				continue
+			}
+
+			if err := validateAction(wcfg.Events[ei].Action); err != nil {
+				c.reportWidget(wcfg, fmt.Errorf("events#%d: %w", ei+1, err))
+
+				continue
+			}
+
+			if name, id := splitAction(wcfg.Events[ei].Action); name == "refresh-widget" && !hasWidget(cfg, id) {
+				c.reportWidget(wcfg, fmt.Errorf("events#%d: widget '%s' not found", ei+1, id))
+			}
+		}
+
+		for fi := range wcfg.Filters {
+			if _, _, err := registry.DecodeFilterParams(wcfg.Filters[fi]); err != nil {
+				c.reportWidget(wcfg, fmt.Errorf("filters#%d: %w", fi+1, err))
+			}
+		}
+	}
+
+	for fi := range cfg.Output.Filters {
+		if _, _, err := registry.DecodeFilterParams(cfg.Output.Filters[fi]); err != nil {
+			c.report(cfg.File, 0, fmt.Sprintf("output.filters#%d: %s", fi+1, err))
+		}
+	}
+
+	if c.problems > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+func hasWidget(cfg *config.Config, id string) bool {
+	for _, wcfg := range cfg.Widgets {
+		if wcfg.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *configChecker) reportWidget(wcfg config.WidgetConfig, err error) {
+	file := c.path(wcfg.File)
+	line := 0
+
+	if pos := c.position(file, wcfg.Index); pos != nil {
+		line = pos.Line
+		msg := err.Error()
+
+		if m := fieldErrRe.FindStringSubmatch(msg); m != nil {
+			if l, ok := pos.Keys[m[1]]; ok {
+				line = l
+			}
+		}
+
+		if m := eventErrRe.FindStringSubmatch(msg); m != nil {
+			if ei, _ := strconv.Atoi(m[1]); ei > 0 && ei <= len(pos.Events) {
+				line = pos.Events[ei-1]
+			}
+		}
+
+		if m := filterErrRe.FindStringSubmatch(msg); m != nil {
+			if fi, _ := strconv.Atoi(m[1]); fi > 0 && fi <= len(pos.Filters) {
+				line = pos.Filters[fi-1]
+			}
+		}
+	}
+
+	c.reportErr(file, line, err)
+}
+
+func (c *configChecker) reportErr(file string, line int, err error) {
+	var fe *config.FileError
+	if errors.As(err, &fe) {
+		c.report(c.path(fe.File), fe.Line, fe.Msg)
+
+		return
+	}
+
+	c.report(file, line, err.Error())
+}
+
+func (c *configChecker) report(file string, line int, msg string) {
+	c.problems++
+
+	switch {
+	case file == "":
+		fmt.Println(msg)
+	case line > 0:
+		fmt.Printf("%s:%d: %s\n", file, line, msg)
+	default:
+		fmt.Printf("%s: %s\n", file, msg)
+	}
+}
+
+func (c *configChecker) position(file string, index int) *config.WidgetPosition {
+	positions, ok := c.positions[file]
+	if !ok {
+		positions, _ = config.WidgetPositions(file)
+		c.positions[file] = positions
+	}
+
+	if index < len(positions) {
+		return &positions[index]
+	}
+
+	return nil
+}
+
+// path returns the path of a config file, the main file is stored by its base name.
+func (c *configChecker) path(file string) string {
+	if file == "" || file == "builtin" || filepath.IsAbs(file) {
+		return file
+	}
+
+	if file == filepath.Base(c.cfg.File) {
+		return c.cfg.File
+	}
+
+	return file
+}
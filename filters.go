@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/burik666/yagostatus/internal/config"
+	"github.com/burik666/yagostatus/internal/registry"
+	"github.com/burik666/yagostatus/ygs"
+)
+
+// newFilters creates output filters by their configs.
+func newFilters(cfgs []config.FilterConfig, logger ygs.Logger) ([]ygs.Filter, error) {
+	filters := make([]ygs.Filter, 0, len(cfgs))
+
+	for fi := range cfgs {
+		flogger := logger.WithPrefix(fmt.Sprintf("[filter %s]", cfgs[fi].Name))
+
+		filter, err := registry.NewFilter(cfgs[fi], flogger)
+		if err != nil {
+			return nil, fmt.Errorf("filters#%d: %w", fi+1, err)
+		}
+
+		filters = append(filters, filter)
+	}
+
+	return filters, nil
+}
+
+// filterWidget applies the widget filters and then the global filters to the widget blocks.
+// If a filter panics, the blocks are returned unfiltered.
+func (status *YaGoStatus) filterWidget(wi int, blocks []ygs.I3BarBlock) (result []ygs.I3BarBlock) {
+	wcfg := status.widgets[wi].config
+
+	if len(status.widgets[wi].filters) == 0 && len(status.filters) == 0 {
+		return blocks
+	}
+
+	defer (func() {
+		if r := recover(); r != nil {
+			status.widgets[wi].logger.Errorf("filter panic: %s", r)
+			debug.PrintStack()
+
+			result = blocks
+		}
+	})()
+
+	info := ygs.WidgetInfo{
+		ID:    wcfg.ID,
+		Name:  wcfg.Name,
+		File:  wcfg.File,
+		Index: wcfg.Index,
+	}
+
+	result = blocks
+
+	for _, f := range status.widgets[wi].filters {
+		result = f.Widget(info, result)
+	}
+
+	for _, f := range status.filters {
+		result = f.Widget(info, result)
+	}
+
+	return result
+}
+
+// filterFrame applies the global filters to all blocks before they are sent to i3bar.
+// If a filter panics, the blocks are returned unfiltered.
+func (status *YaGoStatus) filterFrame(blocks []ygs.I3BarBlock) (result []ygs.I3BarBlock) {
+	if len(status.filters) == 0 {
+		return blocks
+	}
+
+	defer (func() {
+		if r := recover(); r != nil {
+			status.logger.Errorf("filter panic: %s", r)
+			debug.PrintStack()
+
+			result = blocks
+		}
+	})()
+
+	result = blocks
+
+	for _, f := range status.filters {
+		result = f.Frame(result)
+	}
+
+	return result
+}
@@ -6,6 +6,7 @@ require (
 	go.i3wm.org/i3/v4 v4.21.0
 	golang.org/x/net v0.35.0
 	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
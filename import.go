@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/burik666/yagostatus/internal/i3blocks"
+	"github.com/burik666/yagostatus/ygs"
+)
+
+// importConfig converts the config of another status program (import i3blocks <file>),
+// prints the yagostatus config to stdout and the untranslated properties to stderr.
+func importConfig(args []string, logger ygs.Logger) int {
+	if len(args) != 2 || args[0] != "i3blocks" {
+		logger.Errorf("usage: yagostatus import i3blocks <file>")
+
+		return 2
+	}
+
+	f, err := os.Open(args[1])
+	if err != nil {
+		logger.Errorf("Failed to import config: %s", err)
+
+		return 1
+	}
+
+	defer f.Close()
+
+	b, warnings, err := i3blocks.Import(f, args[1])
+	if err != nil {
+		logger.Errorf("Failed to import config: %s", err)
+
+		return 1
+	}
+
+	for _, w := range warnings {
+		fmt.Fprintln(os.Stderr, w)
+	}
+
+	_, _ = os.Stdout.Write(b)
+
+	return 0
+}
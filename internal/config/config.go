@@ -1,27 +1,34 @@
 package config
 
 import (
+	"bytes"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"syscall"
 
+	"github.com/burik666/yagostatus/internal/source"
 	"gopkg.in/yaml.v2"
 )
 
 // Config represents the main configuration.
 type Config struct {
+	Include []string `yaml:"include,omitempty"`
 	Signals struct {
-		StopSignal syscall.Signal `yaml:"stop"`
-		ContSignal syscall.Signal `yaml:"cont"`
+		StopSignal syscall.Signal `yaml:"stop" description:"Signal to stop widgets (default: SIGUSR1)."`
+		ContSignal syscall.Signal `yaml:"cont" description:"Signal to continue widgets (default: SIGCONT)."`
 	} `yaml:"signals"`
 	Plugins struct {
 		Path string         `yaml:"path"`
 		Load []PluginConfig `yaml:"load"`
 	} `yaml:"plugins"`
-	Variables map[string]interface{} `yaml:"variables"`
-	Widgets   []WidgetConfig         `yaml:"widgets"`
-	File      string                 `yaml:"-"`
+	Output    OutputConfig             `yaml:"output"`
+	Sources   map[string]source.Params `yaml:"sources,omitempty"`
+	Variables map[string]interface{}   `yaml:"variables"`
+	Widgets   []WidgetConfig           `yaml:"widgets"`
+	File      string                   `yaml:"-"`
+	Included  []string                 `yaml:"-"`
 }
 
 // SnippetConfig represents the snippet configuration.
@@ -73,5 +80,27 @@ func Parse(data []byte, source string) (*Config, error) {
 
 // Dump dumps config.
 func Dump(cfg *Config) ([]byte, error) {
-	return yaml.Marshal(cfg)
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cfg.Included) == 0 {
+		return b, nil
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteString("# Merged from included files (in order):\n")
+
+	for _, f := range cfg.Included {
+		fmt.Fprintf(&buf, "#   - %s\n", f)
+	}
+
+	buf.WriteString("# Widgets of included files are placed before the widgets of the including file.\n")
+	buf.WriteString("# Variables and settings of later files override earlier ones,\n")
+	buf.WriteString("# the including file overrides all files it includes.\n")
+	buf.Write(b)
+
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,188 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// ParamsDecoder decodes widget parameters merged with the widget defaults.
+type ParamsDecoder func(wcfg WidgetConfig) (interface{}, error)
+
+// DumpResolved dumps config with widgets after snippet expansion and variables substitution,
+// merged with their default parameters and annotated with their origin.
+func DumpResolved(cfg *Config, decode ParamsDecoder) ([]byte, error) {
+	doc := &yamlv3.Node{Kind: yamlv3.MappingNode}
+
+	if err := addNode(doc, "signals", cfg.Signals); err != nil {
+		return nil, err
+	}
+
+	if err := addNode(doc, "plugins", cfg.Plugins); err != nil {
+		return nil, err
+	}
+
+	if err := addNode(doc, "output", cfg.Output); err != nil {
+		return nil, err
+	}
+
+	if len(cfg.Sources) > 0 {
+		if err := addNode(doc, "sources", cfg.Sources); err != nil {
+			return nil, err
+		}
+	}
+
+	widgets := &yamlv3.Node{Kind: yamlv3.SequenceNode}
+
+	for _, wcfg := range cfg.Widgets {
+		w, err := resolvedWidget(wcfg, decode)
+		if err != nil {
+			return nil, fmt.Errorf("%s#%d: %w", wcfg.File, wcfg.Index+1, err)
+		}
+
+		widgets.Content = append(widgets.Content, w)
+	}
+
+	doc.Content = append(doc.Content, &yamlv3.Node{Kind: yamlv3.ScalarNode, Value: "widgets"}, widgets)
+
+	if cfg.File != "" {
+		doc.HeadComment = fmt.Sprintf("resolved config: %s", cfg.File)
+	}
+
+	var buf bytes.Buffer
+
+	enc := yamlv3.NewEncoder(&buf)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func resolvedWidget(wcfg WidgetConfig, decode ParamsDecoder) (*yamlv3.Node, error) {
+	node := &yamlv3.Node{Kind: yamlv3.MappingNode}
+
+	comments := []string{fmt.Sprintf("%s#%d", wcfg.File, wcfg.Index+1)}
+	if len(wcfg.IncludeStack) > 0 {
+		comments = append(comments, fmt.Sprintf("include stack: %s", strings.Join(wcfg.IncludeStack, " -> ")))
+	}
+
+	var params interface{}
+
+	if wcfg.Err != nil {
+		comments = append(comments, fmt.Sprintf("error: %s", wcfg.Err))
+	} else {
+		p, err := decode(wcfg)
+		if err != nil {
+			comments = append(comments, fmt.Sprintf("error: %s", err))
+
+			params = wcfg.Params
+		} else {
+			params = p
+		}
+	}
+
+	// yaml.v2 is used to marshal params, it knows the yaml names of the params fields.
+	var pnode yamlv3.Node
+
+	if params != nil {
+		b, err := yaml.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := yamlv3.Unmarshal(b, &pnode); err != nil {
+			return nil, err
+		}
+	}
+
+	node.HeadComment = strings.Join(comments, "\n")
+
+	if err := addNode(node, "widget", wcfg.Name); err != nil {
+		return nil, err
+	}
+
+	if wcfg.ID != "" {
+		if err := addNode(node, "id", wcfg.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(wcfg.Workspaces) > 0 {
+		if err := addNode(node, "workspaces", wcfg.Workspaces); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := addNode(node, "workdir", wcfg.WorkDir); err != nil {
+		return nil, err
+	}
+
+	if len(pnode.Content) > 0 && pnode.Content[0].Kind == yamlv3.MappingNode {
+		pm := pnode.Content[0].Content
+		for i := 0; i+1 < len(pm); i += 2 {
+			if pm[i].Value == "workdir" {
+				continue
+			}
+
+			node.Content = append(node.Content, pm[i], pm[i+1])
+		}
+	}
+
+	if wcfg.Err != nil {
+		if err := addNode(node, "blocks", wcfg.Params["blocks"]); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(wcfg.Templates) > 0 {
+		b, err := json.Marshal(wcfg.Templates)
+		if err != nil {
+			return nil, err
+		}
+
+		var tpls []interface{}
+		if err := json.Unmarshal(b, &tpls); err != nil {
+			return nil, err
+		}
+
+		if err := addNode(node, "templates", tpls); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(wcfg.Events) > 0 {
+		if err := addNode(node, "events", wcfg.Events); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(wcfg.Filters) > 0 {
+		if err := addNode(node, "filters", wcfg.Filters); err != nil {
+			return nil, err
+		}
+	}
+
+	return node, nil
+}
+
+func addNode(mapping *yamlv3.Node, key string, value interface{}) error {
+	var v yamlv3.Node
+	if err := v.Encode(value); err != nil {
+		return err
+	}
+
+	mapping.Content = append(mapping.Content, &yamlv3.Node{Kind: yamlv3.ScalarNode, Value: key}, &v)
+
+	return nil
+}
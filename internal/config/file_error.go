@@ -0,0 +1,38 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var lineRe = regexp.MustCompile(`^line (\d+): `)
+
+// FileError represents an error in a config file.
+type FileError struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func newFileError(file string, err error) *FileError {
+	fe := &FileError{
+		File: file,
+		Msg:  err.Error(),
+	}
+
+	if m := lineRe.FindStringSubmatch(fe.Msg); m != nil {
+		fe.Line, _ = strconv.Atoi(m[1])
+		fe.Msg = fe.Msg[len(m[0]):]
+	}
+
+	return fe
+}
+
+func (e *FileError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+	}
+
+	return e.Msg
+}
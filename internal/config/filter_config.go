@@ -0,0 +1,18 @@
+package config
+
+import "errors"
+
+// FilterConfig represents an output filter configuration.
+type FilterConfig struct {
+	Name   string                 `yaml:"filter"`
+	Params map[string]interface{} `yaml:",inline"`
+}
+
+// Validate checks filter configuration.
+func (c FilterConfig) Validate() error {
+	if c.Name == "" {
+		return errors.New("missing filter name")
+	}
+
+	return nil
+}
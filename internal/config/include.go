@@ -0,0 +1,190 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/burik666/yagostatus/internal/source"
+	"gopkg.in/yaml.v2"
+)
+
+// load parses a config file and merges its includes.
+// stack contains the files being loaded and is used to detect recursive includes.
+func load(data []byte, workdir string, source string, stack []string) (*Config, error) {
+	config := Config{}
+
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, newFileError(source, trimYamlErr(err, false))
+	}
+
+	for wi := range config.Widgets {
+		config.Widgets[wi].File = source
+		config.Widgets[wi].Index = wi
+
+		if config.Widgets[wi].WorkDir == "" {
+			config.Widgets[wi].WorkDir = workdir
+		}
+	}
+
+	for name, s := range config.Sources {
+		if s.WorkDir == "" {
+			s.WorkDir = workdir
+			config.Sources[name] = s
+		}
+	}
+
+	if len(config.Include) == 0 {
+		return &config, nil
+	}
+
+	files, err := resolveIncludes(config.Include, workdir)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := Config{}
+
+	for _, filename := range files {
+		for i := range stack {
+			if filename == stack[i] {
+				//nolint:gocritic
+				s := append(stack, filename)
+
+				return nil, fmt.Errorf("recursive include: '%s'", strings.Join(s, " -> "))
+			}
+		}
+
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		//nolint:gocritic
+		inc, err := load(data, filepath.Dir(filename), filename, append(stack, filename))
+		if err != nil {
+			return nil, fmt.Errorf("include '%s': %w", filename, err)
+		}
+
+		merged.Included = append(merged.Included, filename)
+		merged.merge(inc)
+	}
+
+	config.Include = nil
+	merged.merge(&config)
+
+	return &merged, nil
+}
+
+// resolveIncludes expands include patterns relative to workdir.
+// Directories include all *.yml and *.yaml files in them.
+// Glob matches are sorted to keep the order deterministic.
+func resolveIncludes(patterns []string, workdir string) ([]string, error) {
+	var files []string
+
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(workdir, pattern)
+		}
+
+		globs := []string{pattern}
+
+		if !hasGlobMeta(pattern) {
+			fi, err := os.Stat(pattern)
+			if err != nil {
+				return nil, err
+			}
+
+			if !fi.IsDir() {
+				files = append(files, pattern)
+
+				continue
+			}
+
+			globs = []string{
+				filepath.Join(pattern, "*.yml"),
+				filepath.Join(pattern, "*.yaml"),
+			}
+		}
+
+		var matches []string
+
+		for _, g := range globs {
+			m, err := filepath.Glob(g)
+			if err != nil {
+				return nil, fmt.Errorf("include '%s': %w", g, err)
+			}
+
+			matches = append(matches, m...)
+		}
+
+		sort.Strings(matches)
+
+		files = append(files, matches...)
+	}
+
+	return files, nil
+}
+
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, `*?[\`)
+}
+
+// merge merges src into c, values from src take precedence.
+func (c *Config) merge(src *Config) {
+	if src.Signals.StopSignal != 0 {
+		c.Signals.StopSignal = src.Signals.StopSignal
+	}
+
+	if src.Signals.ContSignal != 0 {
+		c.Signals.ContSignal = src.Signals.ContSignal
+	}
+
+	if src.Plugins.Path != "" {
+		c.Plugins.Path = src.Plugins.Path
+	}
+
+	c.Plugins.Load = append(c.Plugins.Load, src.Plugins.Load...)
+
+	sep := src.Output.Separators
+
+	if sep.Mode != "" {
+		c.Output.Separators.Mode = sep.Mode
+	}
+
+	if sep.Glyph != "" {
+		c.Output.Separators.Glyph = sep.Glyph
+	}
+
+	if sep.Background != "" {
+		c.Output.Separators.Background = sep.Background
+	}
+
+	if sep.Markup != "" {
+		c.Output.Separators.Markup = sep.Markup
+	}
+
+	c.Output.Filters = append(c.Output.Filters, src.Output.Filters...)
+
+	if len(src.Sources) > 0 && c.Sources == nil {
+		c.Sources = make(map[string]source.Params, len(src.Sources))
+	}
+
+	for name, s := range src.Sources {
+		c.Sources[name] = s
+	}
+
+	if len(src.Variables) > 0 && c.Variables == nil {
+		c.Variables = make(map[string]interface{}, len(src.Variables))
+	}
+
+	for k, v := range src.Variables {
+		c.Variables[k] = v
+	}
+
+	c.Widgets = append(c.Widgets, src.Widgets...)
+	c.Included = append(c.Included, src.Included...)
+}
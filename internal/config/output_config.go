@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+)
+
+// Separators modes.
+const (
+	SeparatorsModeNone      = "none"
+	SeparatorsModePowerline = "powerline"
+)
+
+// OutputConfig represents the bar output configuration.
+type OutputConfig struct {
+	Separators SeparatorsConfig `yaml:"separators"`
+	Filters    []FilterConfig   `yaml:"filters,omitempty"`
+}
+
+// SeparatorsConfig represents the automatic separators between widgets.
+type SeparatorsConfig struct {
+	Mode       string `yaml:"mode" description:"Separators mode: none or powerline."`
+	Glyph      string `yaml:"glyph" description:"Separator text."`
+	Background string `yaml:"background" description:"Bar background color."`
+	Markup     string `yaml:"markup,omitempty" description:"Separator markup."`
+}
+
+// Validate checks output parameters.
+func (c *OutputConfig) Validate() error {
+	if err := c.Separators.Validate(); err != nil {
+		return fmt.Errorf("output.separators: %w", err)
+	}
+
+	for fi := range c.Filters {
+		if err := c.Filters[fi].Validate(); err != nil {
+			return fmt.Errorf("output.filters#%d: %w", fi+1, err)
+		}
+	}
+
+	return nil
+}
+
+// Validate checks separators parameters.
+func (c *SeparatorsConfig) Validate() error {
+	if c.Mode == "" {
+		c.Mode = SeparatorsModeNone
+	}
+
+	switch c.Mode {
+	case SeparatorsModeNone:
+	case SeparatorsModePowerline:
+		if c.Glyph == "" {
+			c.Glyph = "\ue0b2"
+		}
+	default:
+		return fmt.Errorf("unknown mode '%s' (may be '%s' or '%s')", c.Mode, SeparatorsModeNone, SeparatorsModePowerline)
+	}
+
+	return nil
+}
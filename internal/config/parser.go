@@ -20,9 +20,20 @@ import (
 )
 
 func parse(data []byte, workdir string, source string) (*Config, error) {
-	config := Config{}
-	config.Signals.StopSignal = syscall.SIGUSR1
-	config.Signals.ContSignal = syscall.SIGCONT
+	pconfig, err := load(data, workdir, source, []string{filepath.Join(workdir, source)})
+	if err != nil {
+		return nil, err
+	}
+
+	config := *pconfig
+
+	if config.Signals.StopSignal == 0 {
+		config.Signals.StopSignal = syscall.SIGUSR1
+	}
+
+	if config.Signals.ContSignal == 0 {
+		config.Signals.ContSignal = syscall.SIGCONT
+	}
 
 	if config.Plugins.Path == "" {
 		wd, err := os.Getwd()
@@ -33,35 +44,38 @@ func parse(data []byte, workdir string, source string) (*Config, error) {
 		config.Plugins.Path = wd
 	}
 
-	if err := yaml.UnmarshalStrict(data, &config); err != nil {
-		return nil, trimYamlErr(err, false)
-	}
-
-	for wi := range config.Widgets {
-		config.Widgets[wi].File = source
-		config.Widgets[wi].Index = wi
+	if err := config.Output.Validate(); err != nil {
+		return nil, err
 	}
 
-	dict := make(map[string]string, len(config.Variables))
+	vars := make(map[string]variable, len(config.Variables))
 
 	for k, v := range config.Variables {
-		vb, err := json.Marshal(v)
+		s, err := varyString(v)
 		if err != nil {
 			return nil, err
 		}
 
-		var vraw ygs.Vary
+		vars[k] = variable{
+			value:  s,
+			expand: true,
+		}
+	}
+
+	execCache := make(map[string]string)
 
-		err = json.Unmarshal(vb, &vraw)
-		if err != nil {
+	for name, s := range config.Sources {
+		if err := validateSourceName(name); err != nil {
 			return nil, err
 		}
 
-		dict[fmt.Sprintf("${%s}", k)] = strings.TrimRight(vraw.String(), "\n")
-	}
+		v := reflect.ValueOf(&s).Elem()
+		if err := replaceRecursive(&v, newExpander(vars, s.WorkDir, execCache)); err != nil {
+			return nil, fmt.Errorf("source '%s': %w", name, err)
+		}
 
-	v := reflect.ValueOf(config.Widgets)
-	replaceRecursive(&v, dict)
+		config.Sources[name] = s
+	}
 
 WIDGET:
 	for wi := 0; wi < len(config.Widgets); wi++ {
@@ -69,15 +83,36 @@ WIDGET:
 
 		l := logger.WithPrefix(fmt.Sprintf("[%s#%d]", widget.File, widget.Index+1))
 
+		if widget.WorkDir == "" {
+			widget.WorkDir = workdir
+		}
+
+		// snippet widgets are expanded with the snippet variables
+		if len(widget.IncludeStack) == 0 {
+			v := reflect.ValueOf(widget).Elem()
+			if err := replaceRecursive(&v, newExpander(vars, widget.WorkDir, execCache)); err != nil {
+				setError(widget, err, false)
+
+				continue WIDGET
+			}
+		}
+
+		if len(widget.IncludeStack) == 0 {
+			if widget.ID == "" {
+				widget.ID = autoID(workdir, widget.File, widget.Index)
+			} else if err := ValidateID(widget.ID); err != nil {
+				setError(widget, err, false)
+				widget.ID = ""
+
+				continue WIDGET
+			}
+		}
+
 		params := config.Widgets[wi].Params
 		if params == nil {
 			params = make(map[string]interface{})
 		}
 
-		if widget.WorkDir == "" {
-			widget.WorkDir = workdir
-		}
-
 		for i := range widget.Events {
 			if widget.Events[i].WorkDir == "" {
 				widget.Events[i].WorkDir = widget.WorkDir
@@ -86,37 +121,37 @@ WIDGET:
 
 		// for backward compatibility
 		if itpl, ok := params["template"]; ok {
-			tpl, ok := itpl.(string)
-			if !ok {
-				setError(widget, fmt.Errorf("invalid template"), false)
+			tpl, err := ygs.BlocksJSONFromYAML(itpl)
+			if err != nil {
+				setError(widget, fmt.Errorf("invalid template: %w", err), false)
 
 				continue WIDGET
 			}
 
 			widget.Templates = append(widget.Templates, ygs.I3BarBlock{})
-			if err := json.Unmarshal([]byte(tpl), &widget.Templates[0]); err != nil {
-				setError(widget, err, false)
+			if err := json.Unmarshal(tpl, &widget.Templates[0]); err != nil {
+				setError(widget, fmt.Errorf("invalid template: %w", err), false)
 
 				continue WIDGET
 			}
 		}
 
 		if itpls, ok := params["templates"]; ok {
-			tpls, ok := itpls.(string)
-			if !ok {
-				setError(widget, fmt.Errorf("invalid template"), false)
+			tpls, err := ygs.BlocksJSONFromYAML(itpls)
+			if err != nil {
+				setError(widget, fmt.Errorf("invalid templates: %w", err), false)
 
 				continue WIDGET
 			}
 
-			if err := json.Unmarshal([]byte(tpls), &widget.Templates); err != nil {
-				setError(widget, err, false)
+			if err := json.Unmarshal(tpls, &widget.Templates); err != nil {
+				setError(widget, fmt.Errorf("invalid templates: %w", err), false)
 
 				continue WIDGET
 			}
 		}
 
-		ok, err := parseSnippet(&config, wi, params)
+		ok, err := parseSnippet(&config, wi, params, execCache)
 		if err != nil {
 			l.Errorf("parse snippets: %s", err)
 
@@ -138,10 +173,13 @@ WIDGET:
 		}
 	}
 
+	checkIDs(config.Widgets)
+	checkSources(config.Widgets, config.Sources)
+
 	return &config, nil
 }
 
-func parseSnippet(config *Config, wi int, params map[string]interface{}) (bool, error) {
+func parseSnippet(config *Config, wi int, params map[string]interface{}, execCache map[string]string) (bool, error) {
 	widget := config.Widgets[wi]
 
 	if len(widget.Name) > 0 && widget.Name[0] == '$' {
@@ -167,16 +205,10 @@ func parseSnippet(config *Config, wi int, params map[string]interface{}) (bool,
 
 		var snippetConfig SnippetConfig
 		if err := yaml.UnmarshalStrict(data, &snippetConfig); err != nil {
-			return false, trimYamlErr(err, false)
-		}
-
-		for k, v := range snippetConfig.Variables {
-			if _, ok := params[k]; !ok {
-				params[k] = v
-			}
+			return false, newFileError(filename, trimYamlErr(err, false))
 		}
 
-		dict := make(map[string]string, len(params))
+		vars := make(map[string]variable, len(snippetConfig.Variables))
 
 		for k, v := range params {
 			if k == "template" || k == "templates" {
@@ -187,31 +219,45 @@ func parseSnippet(config *Config, wi int, params map[string]interface{}) (bool,
 				return false, fmt.Errorf("unknown variable '%s'", k)
 			}
 
-			vb, err := json.Marshal(v)
+			s, err := varyString(v)
 			if err != nil {
 				return false, err
 			}
 
-			var vraw ygs.Vary
+			// parameters are already expanded in the including file
+			vars[k] = variable{
+				value: s,
+			}
+		}
+
+		for k, v := range snippetConfig.Variables {
+			if _, ok := vars[k]; ok {
+				continue
+			}
 
-			err = json.Unmarshal(vb, &vraw)
+			s, err := varyString(v)
 			if err != nil {
 				return false, err
 			}
 
-			dict[fmt.Sprintf("${%s}", k)] = strings.TrimRight(vraw.String(), "\n")
+			vars[k] = variable{
+				value:  s,
+				expand: true,
+			}
 		}
 
+		wd = filepath.Dir(filename)
+
 		v := reflect.ValueOf(snippetConfig.Widgets)
-		replaceRecursive(&v, dict)
+		if err := replaceRecursive(&v, newExpander(vars, wd, execCache)); err != nil {
+			return false, err
+		}
 
 		var tpls []byte
 		if len(widget.Templates) > 0 {
 			tpls, _ = json.Marshal(widget.Templates)
 		}
 
-		wd = filepath.Dir(filename)
-
 		for i := range snippetConfig.Widgets {
 			if snippetConfig.Widgets[i].WorkDir == "" {
 				snippetConfig.Widgets[i].WorkDir = wd
@@ -219,6 +265,16 @@ func parseSnippet(config *Config, wi int, params map[string]interface{}) (bool,
 
 			snippetConfig.Widgets[i].File = filename
 			snippetConfig.Widgets[i].Index = i
+
+			// snippet widgets ids are prefixed with the id of the including widget
+			id := snippetConfig.Widgets[i].ID
+			if id == "" {
+				id = autoID(wd, filename, i)
+			} else if err := ValidateID(id); err != nil {
+				return false, err
+			}
+
+			snippetConfig.Widgets[i].ID = widget.ID + "." + id
 			//nolint:gocritic
 			snippetConfig.Widgets[i].IncludeStack = append(widget.IncludeStack, widget.Name)
 			if tpls != nil {
@@ -259,6 +315,7 @@ func parseSnippet(config *Config, wi int, params map[string]interface{}) (bool,
 			}
 
 			snippetConfig.Widgets[i].Events = snipEvents
+			snippetConfig.Widgets[i].Filters = append(snippetConfig.Widgets[i].Filters, widget.Filters...)
 		}
 
 		config.Widgets = append(config.Widgets[:wi], config.Widgets[wi+1:]...)
@@ -271,7 +328,19 @@ func parseSnippet(config *Config, wi int, params map[string]interface{}) (bool,
 }
 
 func setError(widget *WidgetConfig, err error, trimLineN bool) {
-	*widget = ErrorWidget(trimYamlErr(err, trimLineN).Error())
+	var fe *FileError
+	if !errors.As(err, &fe) {
+		err = trimYamlErr(err, trimLineN)
+	}
+
+	ew := ErrorWidget(err.Error())
+	ew.ID = widget.ID
+	ew.File = widget.File
+	ew.Index = widget.Index
+	ew.IncludeStack = widget.IncludeStack
+	ew.Err = err
+
+	*widget = ew
 }
 
 func trimYamlErr(err error, trimLineN bool) error {
@@ -286,7 +355,7 @@ func trimYamlErr(err error, trimLineN bool) error {
 	return errors.New(msg)
 }
 
-func replaceRecursive(v *reflect.Value, dict map[string]string) {
+func replaceRecursive(v *reflect.Value, exp *expander) error {
 	vv := *v
 	for vv.Kind() == reflect.Ptr || vv.Kind() == reflect.Interface {
 		vv = vv.Elem()
@@ -296,25 +365,33 @@ func replaceRecursive(v *reflect.Value, dict map[string]string) {
 	case reflect.Slice, reflect.Array:
 		for i := 0; i < vv.Len(); i++ {
 			vi := vv.Index(i)
-			replaceRecursive(&vi, dict)
+			if err := replaceRecursive(&vi, exp); err != nil {
+				return err
+			}
+
 			vv.Index(i).Set(vi)
 		}
 	case reflect.Map:
 		for _, i := range vv.MapKeys() {
 			vm := vv.MapIndex(i)
-			replaceRecursive(&vm, dict)
+			if err := replaceRecursive(&vm, exp); err != nil {
+				return fmt.Errorf("%v: %w", i, err)
+			}
+
 			vv.SetMapIndex(i, vm)
 		}
 	case reflect.Struct:
 		t := vv.Type()
 		for i := 0; i < t.NumField(); i++ {
 			vf := v.Field(i)
-			replaceRecursive(&vf, dict)
+			if err := replaceRecursive(&vf, exp); err != nil {
+				return err
+			}
 		}
 	case reflect.String:
-		st := vv.String()
-		for s, r := range dict {
-			st = strings.ReplaceAll(st, s, r)
+		st, err := exp.expand(vv.String())
+		if err != nil {
+			return err
 		}
 
 		if n, err := strconv.ParseInt(st, 10, 64); err == nil {
@@ -322,7 +399,7 @@ func replaceRecursive(v *reflect.Value, dict map[string]string) {
 			vi.SetInt(n)
 			*v = vi
 
-			return
+			return nil
 		}
 
 		if vv.CanSet() {
@@ -333,4 +410,22 @@ func replaceRecursive(v *reflect.Value, dict map[string]string) {
 			*v = vn
 		}
 	}
+
+	return nil
+}
+
+// varyString converts a variable value to string.
+func varyString(v interface{}) (string, error) {
+	vb, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	var vraw ygs.Vary
+
+	if err := json.Unmarshal(vb, &vraw); err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(vraw.String(), "\n"), nil
 }
@@ -0,0 +1,239 @@
+package config
+
+import (
+	"debug/buildinfo"
+	"errors"
+	"fmt"
+	"plugin"
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/burik666/yagostatus/ygs"
+	"gopkg.in/yaml.v2"
+)
+
+// openPlugin opens the go plugin file and returns its spec.
+// Failures are annotated with the differences between the plugin and yagostatus builds.
+func openPlugin(fname string) (ygs.PluginSpec, error) {
+	p, err := plugin.Open(fname)
+	if err != nil {
+		return ygs.PluginSpec{}, buildError(fname, err)
+	}
+
+	sym, err := p.Lookup("Plugin")
+	if err != nil {
+		return ygs.PluginSpec{}, fmt.Errorf("variable Plugin: %w", err)
+	}
+
+	specp, ok := sym.(*ygs.PluginSpec)
+	if !ok {
+		err := fmt.Errorf("variable Plugin is %T, not *ygs.PluginSpec", sym)
+
+		// the spec of another yagostatus version
+		if v := reflect.Indirect(reflect.ValueOf(sym)); v.Kind() == reflect.Struct {
+			if av := v.FieldByName("APIVersion"); av.IsValid() && av.CanInt() {
+				err = fmt.Errorf("%w (plugin API version %d, yagostatus API version %d)", err, av.Int(), ygs.APIVersion)
+			}
+		}
+
+		return ygs.PluginSpec{}, buildError(fname, err)
+	}
+
+	return *specp, nil
+}
+
+// checkPlugin checks that the plugin spec is compatible with yagostatus.
+func checkPlugin(spec ygs.PluginSpec) error {
+	switch {
+	case spec.APIVersion == 0:
+		return errors.New("plugin API version is not set (set APIVersion to ygs.APIVersion and rebuild the plugin)")
+	case spec.APIVersion != ygs.APIVersion:
+		return fmt.Errorf("plugin API version %d is not supported, yagostatus %s supports API version %d (rebuild the plugin)",
+			spec.APIVersion, ygs.Version, ygs.APIVersion)
+	}
+
+	if spec.MinHostVersion != "" {
+		c, err := compareVersions(ygs.Version, spec.MinHostVersion)
+		if err != nil {
+			return fmt.Errorf("invalid MinHostVersion: %w", err)
+		}
+
+		if c < 0 {
+			return fmt.Errorf("plugin requires yagostatus %s or later, found %s", spec.MinHostVersion, ygs.Version)
+		}
+	}
+
+	return nil
+}
+
+// buildError appends the build differences to the error.
+func buildError(fname string, err error) error {
+	diff := buildDiff(fname)
+	if len(diff) == 0 {
+		return err
+	}
+
+	return fmt.Errorf("%w (%s)", err, strings.Join(diff, "; "))
+}
+
+// buildDiff compares the build info of the plugin file with yagostatus.
+func buildDiff(fname string) []string {
+	pbi, err := buildinfo.ReadFile(fname)
+	if err != nil {
+		return nil
+	}
+
+	hbi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+
+	var diff []string
+
+	if pbi.GoVersion != hbi.GoVersion {
+		diff = append(diff, fmt.Sprintf("go: plugin %s, yagostatus %s", pbi.GoVersion, hbi.GoVersion))
+	}
+
+	host := moduleVersions(hbi)
+	modules := moduleVersions(pbi)
+
+	paths := make([]string, 0, len(modules))
+	for path := range modules {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if hv, ok := host[path]; ok && hv != modules[path] {
+			diff = append(diff, fmt.Sprintf("module %s: plugin %s, yagostatus %s", path, modules[path], hv))
+		}
+	}
+
+	return diff
+}
+
+func moduleVersions(bi *debug.BuildInfo) map[string]string {
+	versions := make(map[string]string, len(bi.Deps)+1)
+
+	for _, m := range append([]*debug.Module{&bi.Main}, bi.Deps...) {
+		if m.Path == "" {
+			continue
+		}
+
+		v := m.Version
+		if m.Replace != nil {
+			v = strings.TrimSpace(fmt.Sprintf("%s => %s %s", v, m.Replace.Path, m.Replace.Version))
+		}
+
+		versions[m.Path] = v
+	}
+
+	return versions
+}
+
+// compareVersions compares versions like 1.2.3 (v prefix and suffixes after - or + are ignored).
+func compareVersions(a, b string) (int, error) {
+	av, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+
+	bv, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range av {
+		switch {
+		case av[i] < bv[i]:
+			return -1, nil
+		case av[i] > bv[i]:
+			return 1, nil
+		}
+	}
+
+	return 0, nil
+}
+
+func parseVersion(s string) ([3]int, error) {
+	var v [3]int
+
+	vs := strings.TrimPrefix(s, "v")
+	if i := strings.IndexAny(vs, "-+"); i >= 0 {
+		vs = vs[:i]
+	}
+
+	parts := strings.Split(vs, ".")
+	if len(parts) > len(v) {
+		return v, fmt.Errorf("invalid version '%s'", s)
+	}
+
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid version '%s'", s)
+		}
+
+		v[i] = n
+	}
+
+	return v, nil
+}
+
+// PluginInfo describes the go plugin file without loading the config,
+// the returned error is set if the plugin is not compatible with yagostatus.
+func PluginInfo(fname string) ([]byte, error) {
+	info := yaml.MapSlice{
+		{Key: "file", Value: fname},
+	}
+
+	if bi, err := buildinfo.ReadFile(fname); err == nil {
+		info = append(info,
+			yaml.MapItem{Key: "go", Value: bi.GoVersion},
+			yaml.MapItem{Key: "path", Value: bi.Path},
+		)
+
+		versions := moduleVersions(bi)
+		modules := yaml.MapSlice{}
+
+		for _, m := range append([]*debug.Module{&bi.Main}, bi.Deps...) {
+			if v, ok := versions[m.Path]; ok {
+				modules = append(modules, yaml.MapItem{Key: m.Path, Value: v})
+			}
+		}
+
+		info = append(info, yaml.MapItem{Key: "modules", Value: modules})
+	}
+
+	info = append(info, yaml.MapItem{Key: "yagostatus", Value: yaml.MapSlice{
+		{Key: "version", Value: ygs.Version},
+		{Key: "api_version", Value: ygs.APIVersion},
+	}})
+
+	spec, err := openPlugin(fname)
+	if err == nil {
+		info = append(info,
+			yaml.MapItem{Key: "name", Value: spec.Name},
+			yaml.MapItem{Key: "api_version", Value: spec.APIVersion},
+			yaml.MapItem{Key: "min_host_version", Value: spec.MinHostVersion},
+			yaml.MapItem{Key: "params", Value: spec.DefaultParams},
+		)
+
+		err = checkPlugin(spec)
+	}
+
+	if err != nil {
+		info = append(info, yaml.MapItem{Key: "error", Value: err.Error()})
+	}
+
+	b, merr := yaml.Marshal(info)
+	if merr != nil {
+		return nil, merr
+	}
+
+	return b, err
+}
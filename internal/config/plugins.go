@@ -3,20 +3,40 @@ package config
 import (
 	"fmt"
 	"path/filepath"
-	"plugin"
 	"reflect"
 
+	"github.com/burik666/yagostatus/internal/extplugin"
 	"github.com/burik666/yagostatus/ygs"
 	"gopkg.in/yaml.v2"
 )
 
 type PluginConfig struct {
-	Plugin string                 `yaml:"plugin"`
+	Plugin string                 `yaml:"plugin,omitempty"`
+	Exec   string                 `yaml:"exec,omitempty"`
 	Params map[string]interface{} `yaml:",inline"`
 }
 
 func LoadPlugins(cfg Config, logger ygs.Logger) error {
 	for _, l := range cfg.Plugins.Load {
+		if l.Exec != "" {
+			if l.Plugin != "" {
+				return fmt.Errorf("plugin '%s': 'plugin' and 'exec' are mutually exclusive", l.Plugin)
+			}
+
+			fname := l.Exec
+			if !filepath.IsAbs(fname) {
+				fname = filepath.Join(cfg.Plugins.Path, fname)
+			}
+
+			logger.Infof("Load exec plugin: %s", fname)
+
+			if err := ygs.RegisterPlugin(extplugin.Spec(fname, cfg.Plugins.Path, l.Params)); err != nil {
+				return fmt.Errorf("failed to register plugin: %w", err)
+			}
+
+			continue
+		}
+
 		fname := l.Plugin
 
 		if !filepath.IsAbs(fname) {
@@ -25,25 +45,15 @@ func LoadPlugins(cfg Config, logger ygs.Logger) error {
 
 		logger.Infof("Load plugin: %s", fname)
 
-		p, err := plugin.Open(fname)
+		spec, err := openPlugin(fname)
 		if err != nil {
 			return err
 		}
 
-		plugin, err := p.Lookup("Plugin")
-		if err != nil {
-			return fmt.Errorf("variable Plugin: %w", err)
+		if err := checkPlugin(spec); err != nil {
+			return fmt.Errorf("plugin '%s': %w", l.Plugin, err)
 		}
 
-		pv := reflect.ValueOf(plugin)
-
-		specp, ok := pv.Interface().(*ygs.PluginSpec)
-		if !ok {
-			return fmt.Errorf("variable Plugin is not a ygs.PluginSpec")
-		}
-
-		spec := (*specp)
-
 		spec.Name = fmt.Sprintf("%s#%s", l.Plugin, spec.Name)
 
 		if spec.DefaultParams != nil {
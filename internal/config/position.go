@@ -0,0 +1,85 @@
+package config
+
+import (
+	"io/ioutil"
+
+	// yaml.v2 has no node API, the line numbers of widgets, keys, events and filters
+	// are read with yaml.v3 (also used to write configs with comments by dump and import).
+	// The config is parsed with yaml.v2 as before.
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// WidgetPosition describes the position of a widget in a config file.
+type WidgetPosition struct {
+	Line    int
+	Keys    map[string]int
+	Events  []int
+	Filters []int
+}
+
+// WidgetPositions returns the positions of the widgets in a config (or snippet) file.
+func WidgetPositions(filename string) ([]WidgetPosition, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	widgets := mappingValue(doc.Content[0], "widgets")
+	if widgets == nil || widgets.Kind != yamlv3.SequenceNode {
+		return nil, nil
+	}
+
+	positions := make([]WidgetPosition, len(widgets.Content))
+
+	for i, w := range widgets.Content {
+		positions[i] = WidgetPosition{
+			Line: w.Line,
+			Keys: make(map[string]int),
+		}
+
+		if w.Kind != yamlv3.MappingNode {
+			continue
+		}
+
+		for ki := 0; ki+1 < len(w.Content); ki += 2 {
+			positions[i].Keys[w.Content[ki].Value] = w.Content[ki].Line
+		}
+
+		if events := mappingValue(w, "events"); events != nil {
+			for _, e := range events.Content {
+				positions[i].Events = append(positions[i].Events, e.Line)
+			}
+		}
+
+		if filters := mappingValue(w, "filters"); filters != nil {
+			for _, f := range filters.Content {
+				positions[i].Filters = append(positions[i].Filters, f.Line)
+			}
+		}
+	}
+
+	return positions, nil
+}
+
+func mappingValue(node *yamlv3.Node, key string) *yamlv3.Node {
+	if node.Kind != yamlv3.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
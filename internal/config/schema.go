@@ -0,0 +1,404 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/burik666/yagostatus/pkg/executor"
+	"github.com/burik666/yagostatus/pkg/schedule"
+	"github.com/burik666/yagostatus/ygs"
+)
+
+type schemaNode = map[string]interface{}
+
+// commonWidgetKeys are the keys available for all widgets.
+var commonWidgetKeys = []string{"widget", "id", "workspaces", "template", "templates", "events", "filters", "workdir"}
+
+// Schema generates JSON Schema of the config for registered widgets and plugins.
+func Schema() ([]byte, error) {
+	definitions := schemaNode{
+		"workspaces": schemaNode{
+			"description": "List of workspaces to display the widget (prefix '!' to exclude).",
+			"type":        "array",
+			"items":       schemaNode{"type": "string"},
+		},
+		"block": blockSchema(),
+		"blocks": schemaNode{
+			"anyOf": []interface{}{
+				schemaNode{"type": "string", "description": "JSON list of blocks."},
+				schemaNode{"type": "array", "items": schemaNode{"$ref": "#/definitions/block"}},
+			},
+		},
+		"template": schemaNode{
+			"description": "Template that applies to all widget blocks (deprecated, use templates).",
+			"anyOf": []interface{}{
+				schemaNode{"type": "string", "description": "JSON block."},
+				schemaNode{"$ref": "#/definitions/block"},
+			},
+		},
+		"templates": schemaNode{
+			"description": "List of templates that apply to widget blocks.",
+			"$ref":        "#/definitions/blocks",
+		},
+		"workdir": schemaNode{
+			"description": "Working directory.",
+			"type":        "string",
+		},
+		"id": schemaNode{
+			"description": "Widget id (default: file#index).",
+			"type":        "string",
+			"pattern":     "^[A-Za-z0-9_.-]+$",
+		},
+		"event": structSchema(reflect.TypeOf(WidgetEventConfig{}), reflect.Value{}),
+		"events": schemaNode{
+			"description": "List of commands to be executed on user actions.",
+			"type":        "array",
+			"items":       schemaNode{"$ref": "#/definitions/event"},
+		},
+	}
+
+	widgetRules := make([]interface{}, 0)
+	widgetNames := make([]string, 0)
+
+	widgets := ygs.RegisteredWidgets()
+	sort.Slice(widgets, func(i, j int) bool { return widgets[i].Name < widgets[j].Name })
+
+	for _, ws := range widgets {
+		def := schemaNode{
+			"type":                 "object",
+			"additionalProperties": false,
+		}
+
+		props := schemaNode{}
+
+		if ws.DefaultParams != nil {
+			ps := structSchema(reflect.TypeOf(ws.DefaultParams), reflect.ValueOf(ws.DefaultParams))
+			for k, v := range ps["properties"].(schemaNode) {
+				props[k] = v
+			}
+		}
+
+		for _, k := range commonWidgetKeys {
+			props[k] = schemaNode{"$ref": "#/definitions/" + k}
+		}
+
+		props["widget"] = schemaNode{"const": ws.Name}
+		def["properties"] = props
+
+		definitions["widget_"+ws.Name] = def
+
+		widgetNames = append(widgetNames, ws.Name)
+		widgetRules = append(widgetRules, schemaNode{
+			"if":   schemaNode{"properties": schemaNode{"widget": schemaNode{"const": ws.Name}}},
+			"then": schemaNode{"$ref": "#/definitions/widget_" + ws.Name},
+		})
+	}
+
+	definitions["widget"] = schemaNode{
+		"type":     "object",
+		"required": []string{"widget"},
+		"properties": schemaNode{
+			"widget": schemaNode{
+				"description": "Widget name or snippet file (prefixed with '$').",
+				"anyOf": []interface{}{
+					schemaNode{"enum": widgetNames},
+					schemaNode{"type": "string", "pattern": "^\\$"},
+				},
+			},
+		},
+		"allOf": widgetRules,
+	}
+
+	filterRules := make([]interface{}, 0)
+	filterNames := make([]string, 0)
+
+	filters := ygs.RegisteredFilters()
+	sort.Slice(filters, func(i, j int) bool { return filters[i].Name < filters[j].Name })
+
+	for _, fs := range filters {
+		props := schemaNode{}
+
+		if fs.DefaultParams != nil {
+			ps := structSchema(reflect.TypeOf(fs.DefaultParams), reflect.ValueOf(fs.DefaultParams))
+			props = ps["properties"].(schemaNode)
+		}
+
+		props["filter"] = schemaNode{"const": fs.Name}
+
+		filterNames = append(filterNames, fs.Name)
+		filterRules = append(filterRules, schemaNode{
+			"if": schemaNode{"properties": schemaNode{"filter": schemaNode{"const": fs.Name}}},
+			"then": schemaNode{
+				"properties":           props,
+				"additionalProperties": false,
+			},
+		})
+	}
+
+	definitions["filter"] = schemaNode{
+		"type":     "object",
+		"required": []string{"filter"},
+		"properties": schemaNode{
+			"filter": schemaNode{
+				"description": "Filter name.",
+				"enum":        filterNames,
+			},
+		},
+		"allOf": filterRules,
+	}
+	definitions["filters"] = schemaNode{
+		"description": "List of output filters.",
+		"type":        "array",
+		"items":       schemaNode{"$ref": "#/definitions/filter"},
+	}
+
+	pluginRules := make([]interface{}, 0)
+
+	plugins := ygs.RegisteredPlugins()
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+
+	for _, ps := range plugins {
+		// only loaded plugins have parameters in the config
+		file := strings.SplitN(ps.Name, "#", 2)
+		if len(file) != 2 {
+			continue
+		}
+
+		props := schemaNode{}
+
+		if ps.DefaultParams != nil {
+			s := structSchema(reflect.TypeOf(ps.DefaultParams), reflect.ValueOf(ps.DefaultParams))
+			props = s["properties"].(schemaNode)
+		}
+
+		props["plugin"] = schemaNode{"const": file[0]}
+
+		pluginRules = append(pluginRules, schemaNode{
+			"if": schemaNode{"properties": schemaNode{"plugin": schemaNode{"const": file[0]}}},
+			"then": schemaNode{
+				"properties":           props,
+				"additionalProperties": false,
+			},
+		})
+	}
+
+	cfgType := reflect.TypeOf(Config{})
+	signals, _ := cfgType.FieldByName("Signals")
+	output, _ := cfgType.FieldByName("Output")
+	sources, _ := cfgType.FieldByName("Sources")
+
+	outputSchema := structSchema(output.Type, reflect.Value{})
+	outputSchema["properties"].(schemaNode)["filters"] = schemaNode{
+		"description": "List of output filters applied to all widgets and to the whole bar.",
+		"$ref":        "#/definitions/filters",
+	}
+
+	schema := schemaNode{
+		"$schema":              "http://json-schema.org/draft-07/schema#",
+		"title":                "YaGoStatus config",
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": schemaNode{
+			"include": schemaNode{
+				"description": "Files, globs and directories to include (relative to the config file).",
+				"type":        "array",
+				"items":       schemaNode{"type": "string"},
+			},
+			"signals": structSchema(signals.Type, reflect.Value{}),
+			"plugins": schemaNode{
+				"type":                 "object",
+				"additionalProperties": false,
+				"properties": schemaNode{
+					"path": schemaNode{
+						"description": "Directory where the plugins are located.",
+						"type":        "string",
+					},
+					"load": schemaNode{
+						"type": "array",
+						"items": schemaNode{
+							"type": "object",
+							"oneOf": []interface{}{
+								schemaNode{"required": []string{"plugin"}},
+								schemaNode{"required": []string{"exec"}},
+							},
+							"properties": schemaNode{
+								"plugin": schemaNode{
+									"description": "Go plugin file (.so).",
+									"type":        "string",
+								},
+								"exec": schemaNode{
+									"description": "Executable plugin file.",
+									"type":        "string",
+								},
+							},
+							"allOf": pluginRules,
+						},
+					},
+				},
+			},
+			"output": outputSchema,
+			"sources": schemaNode{
+				"description":          "Shared data sources, widgets render them with {{name.path}} placeholders.",
+				"type":                 "object",
+				"propertyNames":        schemaNode{"pattern": sourceNameRe.String()},
+				"additionalProperties": structSchema(sources.Type.Elem(), reflect.Value{}),
+			},
+			"variables": schemaNode{
+				"description": "Variables available in widget parameters as ${name}.",
+				"type":        "object",
+			},
+			"widgets": schemaNode{
+				"type":  "array",
+				"items": schemaNode{"$ref": "#/definitions/widget"},
+			},
+		},
+		"definitions": definitions,
+	}
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// structSchema describes struct fields by their yaml names,
+// descriptions are taken from the description tag and defaults from the def value.
+func structSchema(t reflect.Type, def reflect.Value) schemaNode {
+	props := schemaNode{}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		tag := strings.Split(f.Tag.Get("yaml"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+
+		if len(tag) > 1 && tag[1] == "inline" {
+			// embedded structs add their fields, inline maps are described by the callers
+			if f.Type.Kind() == reflect.Struct {
+				var fdef reflect.Value
+				if def.IsValid() {
+					fdef = def.Field(i)
+				}
+
+				for k, v := range structSchema(f.Type, fdef)["properties"].(schemaNode) {
+					props[k] = v
+				}
+			}
+
+			continue
+		}
+
+		name := tag[0]
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+
+		var fdef reflect.Value
+		if def.IsValid() {
+			fdef = def.Field(i)
+		}
+
+		fs := typeSchema(f.Type, fdef)
+
+		if desc, ok := f.Tag.Lookup("description"); ok {
+			fs["description"] = desc
+		}
+
+		props[name] = fs
+	}
+
+	return schemaNode{
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties":           props,
+	}
+}
+
+// blockSchema describes i3bar block fields by their json names, custom fields start with '_'.
+func blockSchema() schemaNode {
+	t := reflect.TypeOf(ygs.I3BarBlock{})
+	props := schemaNode{}
+
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		props[name] = typeSchema(t.Field(i).Type, reflect.Value{})
+	}
+
+	props["min_width"] = schemaNode{"type": []string{"integer", "string"}}
+
+	return schemaNode{
+		"type":                 "object",
+		"properties":           props,
+		"patternProperties":    schemaNode{"^_": schemaNode{}},
+		"additionalProperties": false,
+	}
+}
+
+func typeSchema(t reflect.Type, def reflect.Value) schemaNode {
+	if t == reflect.TypeOf(ygs.I3BarBlocks{}) {
+		return schemaNode{"$ref": "#/definitions/blocks"}
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+
+		if def.IsValid() {
+			def = def.Elem()
+		}
+	}
+
+	if t == reflect.TypeOf(executor.Command{}) {
+		return schemaNode{
+			"oneOf": []interface{}{
+				schemaNode{"type": "string"},
+				schemaNode{"type": "array", "items": schemaNode{"type": "string"}, "minItems": 1},
+			},
+		}
+	}
+
+	if t == reflect.TypeOf(schedule.Duration(0)) {
+		s := schemaNode{"type": []string{"integer", "string"}}
+		if def.IsValid() && !def.IsZero() {
+			s["default"], _ = def.Interface().(schedule.Duration).MarshalYAML()
+		}
+
+		return s
+	}
+
+	var s schemaNode
+
+	switch t.Kind() {
+	case reflect.String:
+		s = schemaNode{"type": "string"}
+	case reflect.Bool:
+		s = schemaNode{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = schemaNode{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s = schemaNode{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		s = schemaNode{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		s = schemaNode{"type": "array", "items": typeSchema(t.Elem(), reflect.Value{})}
+	case reflect.Map:
+		s = schemaNode{"type": "object"}
+	case reflect.Struct:
+		return structSchema(t, def)
+	default:
+		s = schemaNode{}
+	}
+
+	if def.IsValid() && !def.IsZero() {
+		s["default"] = def.Interface()
+	}
+
+	return s
+}
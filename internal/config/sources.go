@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/burik666/yagostatus/internal/source"
+	"github.com/burik666/yagostatus/ygs"
+)
+
+var sourceNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func validateSourceName(name string) error {
+	if !sourceNameRe.MatchString(name) {
+		return fmt.Errorf("invalid source name '%s' (may contain letters, digits, '_' and '-')", name)
+	}
+
+	return nil
+}
+
+// checkSources replaces source widgets referencing unknown sources by error widgets,
+// placeholders of templates are rendered only for declared sources.
+func checkSources(widgets []WidgetConfig, sources map[string]source.Params) {
+	for wi := range widgets {
+		if widgets[wi].Name != "source" {
+			continue
+		}
+
+		refs := make([]string, 0)
+
+		if blocks, ok := widgets[wi].Params["blocks"]; ok {
+			if b, err := ygs.JSONFromYAML(blocks); err == nil {
+				refs = append(refs, source.References(string(b))...)
+			}
+		}
+
+		if path, ok := widgets[wi].Params["path"].(string); ok {
+			refs = append(refs, source.References("{{"+path+"}}")...)
+		}
+
+		for _, name := range refs {
+			if _, ok := sources[name]; !ok {
+				setError(&widgets[wi], fmt.Errorf("source '%s' not found", name), false)
+
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	maxExpandDepth = 16
+	execTimeout    = 10 * time.Second
+)
+
+// variable is a value available for substitution.
+// Values with expand set are expanded when used.
+type variable struct {
+	value  string
+	expand bool
+}
+
+// expander substitutes variables in the config strings.
+//
+// Supported forms:
+//
+//	${name}            - variable
+//	${env:NAME}        - environment variable
+//	${file:path}       - file content (relative to workdir, ~ is the home directory)
+//	${exec:command}    - command output (via sh -c), evaluated once at load
+//	${name:-default}   - default value if the result is empty or undefined
+//	$${...}            - literal ${...}
+//
+// Unknown variables are left as is, with their defaults (e.g. shell variables in commands).
+type expander struct {
+	vars    map[string]variable
+	workdir string
+	cache   map[string]string
+	depth   int
+}
+
+func newExpander(vars map[string]variable, workdir string, cache map[string]string) *expander {
+	return &expander{
+		vars:    vars,
+		workdir: workdir,
+		cache:   cache,
+	}
+}
+
+func (e *expander) expand(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	if e.depth > maxExpandDepth {
+		return "", errors.New("variables nested too deep")
+	}
+
+	var sb strings.Builder
+
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			sb.WriteString(s)
+
+			break
+		}
+
+		if i > 0 && s[i-1] == '$' {
+			sb.WriteString(s[:i])
+			sb.WriteString("{")
+			s = s[i+2:]
+
+			continue
+		}
+
+		end := closingBrace(s[i+2:])
+		if end < 0 {
+			return "", fmt.Errorf("unclosed '${' in '%s'", s[i:])
+		}
+
+		expr := s[i+2 : i+2+end]
+
+		r, err := e.eval(expr)
+		if err != nil {
+			return "", fmt.Errorf("${%s}: %w", expr, err)
+		}
+
+		sb.WriteString(s[:i])
+		sb.WriteString(r)
+		s = s[i+3+end:]
+	}
+
+	return sb.String(), nil
+}
+
+func (e *expander) eval(expr string) (string, error) {
+	name := expr
+	def := ""
+	hasDef := false
+
+	if i := defaultSeparator(expr); i >= 0 {
+		name = expr[:i]
+		def = expr[i+2:]
+		hasDef = true
+	}
+
+	var (
+		res string
+		err error
+	)
+
+	switch {
+	case strings.HasPrefix(name, "env:"):
+		var ok bool
+
+		res, ok = os.LookupEnv(name[4:])
+		if !ok && !hasDef {
+			err = fmt.Errorf("environment variable '%s' is not set", name[4:])
+		}
+	case strings.HasPrefix(name, "file:"):
+		res, err = e.readFile(name[5:])
+	case strings.HasPrefix(name, "exec:"):
+		res, err = e.exec(name[5:])
+	default:
+		v, ok := e.vars[name]
+		if !ok {
+			return "${" + expr + "}", nil
+		}
+
+		res = v.value
+		if v.expand {
+			res, err = e.nested(res)
+		}
+	}
+
+	if hasDef && (err != nil || res == "") {
+		return e.nested(def)
+	}
+
+	return res, err
+}
+
+func (e *expander) nested(s string) (string, error) {
+	ne := *e
+	ne.depth++
+
+	return ne.expand(s)
+}
+
+func (e *expander) readFile(filename string) (string, error) {
+	if filename == "~" || strings.HasPrefix(filename, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+
+		filename = filepath.Join(home, filename[1:])
+	}
+
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(e.workdir, filename)
+	}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(b), "\n"), nil
+}
+
+func (e *expander) exec(command string) (string, error) {
+	if res, ok := e.cache[command]; ok {
+		return res, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), execTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = e.workdir
+
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+
+		return "", err
+	}
+
+	res := strings.TrimRight(string(out), "\n")
+
+	if e.cache != nil {
+		e.cache[command] = res
+	}
+
+	return res, nil
+}
+
+// defaultSeparator returns the index of ':-' outside nested expressions (e.g. ${exec:echo ${A:-b}}).
+func defaultSeparator(s string) int {
+	depth := 0
+
+	for i := 0; i < len(s)-1; i++ {
+		switch {
+		case s[i] == '$' && s[i+1] == '{':
+			depth++
+			i++
+		case s[i] == '}' && depth > 0:
+			depth--
+		case s[i] == ':' && s[i+1] == '-' && depth == 0:
+			return i
+		}
+	}
+
+	return -1
+}
+
+// closingBrace returns the index of the brace closing an expression, nested expressions are skipped.
+func closingBrace(s string) int {
+	depth := 0
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+			depth++
+			i++
+		case s[i] == '}':
+			if depth == 0 {
+				return i
+			}
+
+			depth--
+		}
+	}
+
+	return -1
+}
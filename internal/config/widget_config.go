@@ -10,9 +10,11 @@ import (
 // WidgetConfig represents a widget configuration.
 type WidgetConfig struct {
 	Name       string              `yaml:"widget"`
+	ID         string              `yaml:"id,omitempty"`
 	Workspaces []string            `yaml:"workspaces"`
 	Templates  []ygs.I3BarBlock    `yaml:"-"`
 	Events     []WidgetEventConfig `yaml:"events"`
+	Filters    []FilterConfig      `yaml:"filters,omitempty"`
 	WorkDir    string              `yaml:"workdir"`
 	Index      int                 `yaml:"-"`
 	File       string              `yaml:"-"`
@@ -20,6 +22,9 @@ type WidgetConfig struct {
 	Params map[string]interface{} `yaml:",inline"`
 
 	IncludeStack []string `yaml:"-"`
+
+	// Err is set if the widget was replaced by an error widget.
+	Err error `yaml:"-"`
 }
 
 // Validate checks widget configuration.
@@ -34,5 +39,11 @@ func (c WidgetConfig) Validate() error {
 		}
 	}
 
+	for fi := range c.Filters {
+		if err := c.Filters[fi].Validate(); err != nil {
+			return fmt.Errorf("filters#%d: %w", fi+1, err)
+		}
+	}
+
 	return nil
 }
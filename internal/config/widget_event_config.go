@@ -1,21 +1,24 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 )
 
 // WidgetEventConfig represents a widget events.
 type WidgetEventConfig struct {
-	Command      string   `yaml:"command"`
-	Button       uint8    `yaml:"button"`
-	Modifiers    []string `yaml:"modifiers,omitempty"`
-	Name         string   `yaml:"name,omitempty"`
-	Instance     string   `yaml:"instance,omitempty"`
-	OutputFormat string   `yaml:"output_format,omitempty"`
-	Override     bool     `yaml:"override"`
-	WorkDir      string   `yaml:"workdir"`
-	Env          []string `yaml:"env"`
+	Command      string   `yaml:"command" description:"Command to execute (via sh -c)."`
+	Action       string   `yaml:"action,omitempty" description:"Builtin or plugin action to execute instead of the command."`
+	Target       string   `yaml:"target,omitempty" description:"ID of the widget that receives the command output (default: the clicked widget)."`
+	Button       uint8    `yaml:"button" description:"X11 button ID (0 for any)."`
+	Modifiers    []string `yaml:"modifiers,omitempty" description:"List of X11 modifiers condition."`
+	Name         string   `yaml:"name,omitempty" description:"Filter by block name."`
+	Instance     string   `yaml:"instance,omitempty" description:"Filter by block instance."`
+	OutputFormat string   `yaml:"output_format,omitempty" description:"The command output format: none, text, json, auto, text-stream, json-lines or i3blocks."`
+	Override     bool     `yaml:"override" description:"Override previously defined events with the same conditions."`
+	WorkDir      string   `yaml:"workdir" description:"Working directory."`
+	Env          []string `yaml:"env" description:"Environment variables."`
 
 	Params map[string]interface{} `yaml:",inline"`
 }
@@ -30,6 +33,10 @@ func (e *WidgetEventConfig) Validate() error {
 		}
 	}
 
+	if e.Command != "" && e.Action != "" {
+		return errors.New("'command' and 'action' are mutually exclusive")
+	}
+
 	for _, mod := range e.Modifiers {
 		found := false
 		mod = strings.TrimLeft(mod, "!")
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+)
+
+var (
+	idRe        = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+	idInvalidRe = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+)
+
+// autoID returns the widget id generated from its file and index (e.g. conf.d_cpu.yml-1),
+// characters not allowed in ids are replaced with '_'.
+func autoID(workdir string, file string, index int) string {
+	if filepath.IsAbs(file) {
+		if rel, err := filepath.Rel(workdir, file); err == nil {
+			file = rel
+		}
+	}
+
+	return fmt.Sprintf("%s-%d", idInvalidRe.ReplaceAllString(file, "_"), index+1)
+}
+
+// ValidateID checks that the widget id contains only allowed characters.
+func ValidateID(id string) error {
+	if !idRe.MatchString(id) {
+		return fmt.Errorf("invalid id '%s' (may contain letters, digits, '_', '-' and '.')", id)
+	}
+
+	return nil
+}
+
+// checkIDs replaces widgets with duplicate ids and events with unknown targets by error widgets.
+func checkIDs(widgets []WidgetConfig) {
+	ids := make(map[string]int, len(widgets))
+
+	for wi := range widgets {
+		id := widgets[wi].ID
+		if id == "" {
+			continue
+		}
+
+		if prev, ok := ids[id]; ok {
+			setError(&widgets[wi], fmt.Errorf("duplicate id '%s' (%s#%d)", id, widgets[prev].File, widgets[prev].Index+1), false)
+			widgets[wi].ID = ""
+
+			continue
+		}
+
+		ids[id] = wi
+	}
+
+	for wi := range widgets {
+		for ei, e := range widgets[wi].Events {
+			if e.Target == "" {
+				continue
+			}
+
+			if _, ok := ids[e.Target]; !ok {
+				setError(&widgets[wi], fmt.Errorf("events#%d: target '%s' not found", ei+1, e.Target), false)
+
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package extplugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/burik666/yagostatus/ygs"
+)
+
+// ParamSpec describes a widget parameter.
+type ParamSpec struct {
+	Name        string          `json:"name"`
+	Type        string          `json:"type"`
+	Default     json.RawMessage `json:"default,omitempty"`
+	Description string          `json:"description,omitempty"`
+}
+
+var paramTypes = map[string]reflect.Type{
+	"string":  reflect.TypeOf(""),
+	"integer": reflect.TypeOf(int64(0)),
+	"number":  reflect.TypeOf(float64(0)),
+	"boolean": reflect.TypeOf(false),
+	"array":   reflect.TypeOf([]interface{}{}),
+	"object":  reflect.TypeOf(map[string]interface{}{}),
+	"any":     reflect.TypeOf((*interface{})(nil)).Elem(),
+}
+
+// paramsStruct builds a struct value with the described fields set to their defaults,
+// so plugin parameters are decoded and validated like the parameters of builtin widgets.
+func paramsStruct(specs []ParamSpec) (interface{}, error) {
+	fields := make([]reflect.StructField, 0, len(specs))
+	names := make(map[string]struct{}, len(specs))
+
+	for i, p := range specs {
+		t, ok := paramTypes[p.Type]
+		if !ok {
+			return nil, fmt.Errorf("param '%s': unknown type '%s'", p.Name, p.Type)
+		}
+
+		if p.Name == "" || strings.ContainsAny(p.Name, "\" `") {
+			return nil, fmt.Errorf("param #%d: invalid name '%s'", i+1, p.Name)
+		}
+
+		if _, ok := names[p.Name]; ok {
+			return nil, fmt.Errorf("param '%s': duplicate name", p.Name)
+		}
+
+		names[p.Name] = struct{}{}
+
+		name := fmt.Sprintf("P%d", i)
+		// the workdir field is set by the registry
+		if p.Name == "workdir" {
+			if p.Type != "string" {
+				return nil, fmt.Errorf("param '%s': type should be 'string'", p.Name)
+			}
+
+			name = "WorkDir"
+		}
+
+		tag := fmt.Sprintf(`yaml:"%s"`, p.Name)
+		if p.Description != "" {
+			tag += fmt.Sprintf(" description:%q", p.Description)
+		}
+
+		fields = append(fields, reflect.StructField{
+			Name: name,
+			Type: t,
+			Tag:  reflect.StructTag(tag),
+		})
+	}
+
+	v := reflect.New(reflect.StructOf(fields)).Elem()
+
+	for i, p := range specs {
+		if len(p.Default) == 0 {
+			continue
+		}
+
+		if err := json.Unmarshal(p.Default, v.Field(i).Addr().Interface()); err != nil {
+			return nil, fmt.Errorf("param '%s': invalid default: %w", p.Name, err)
+		}
+	}
+
+	return v.Interface(), nil
+}
+
+// paramsMap converts the params struct to a map by the yaml names of fields.
+func paramsMap(params interface{}) (map[string]json.RawMessage, error) {
+	m := make(map[string]json.RawMessage)
+
+	if params == nil {
+		return m, nil
+	}
+
+	v := reflect.ValueOf(params)
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+
+		b, err := jsonParam(v.Field(i).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("param '%s': %w", name, err)
+		}
+
+		m[name] = b
+	}
+
+	return m, nil
+}
+
+// jsonParam converts a value decoded by yaml to json.
+func jsonParam(v interface{}) (json.RawMessage, error) {
+	// JSONFromYAML expects JSON in strings
+	if s, ok := v.(string); ok {
+		return json.Marshal(s)
+	}
+
+	return ygs.JSONFromYAML(v)
+}
@@ -0,0 +1,107 @@
+package extplugin
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseSpec(t *testing.T) {
+	data := `{"widgets": [{"name": "counter", "params": [
+		{"name": "start", "type": "integer", "default": 1, "description": "Initial value."},
+		{"name": "label", "type": "string"}
+	]}]}`
+
+	var res initializeResult
+	if err := json.Unmarshal([]byte(data), &res); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []WidgetTypeSpec{{
+		Name: "counter",
+		Params: []ParamSpec{
+			{Name: "start", Type: "integer", Default: json.RawMessage("1"), Description: "Initial value."},
+			{Name: "label", Type: "string"},
+		},
+	}}
+
+	if !reflect.DeepEqual(res.Widgets, want) {
+		t.Errorf("got %+v, want %+v", res.Widgets, want)
+	}
+}
+
+func TestParamsStruct(t *testing.T) {
+	specs := []ParamSpec{
+		{Name: "text", Type: "string", Default: json.RawMessage(`"hello"`), Description: "Text."},
+		{Name: "count", Type: "integer", Default: json.RawMessage(`3`)},
+		{Name: "ratio", Type: "number"},
+		{Name: "enabled", Type: "boolean", Default: json.RawMessage(`true`)},
+		{Name: "items", Type: "array"},
+		{Name: "options", Type: "object"},
+		{Name: "value", Type: "any"},
+		{Name: "workdir", Type: "string"},
+	}
+
+	def, err := paramsStruct(specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dt := reflect.TypeOf(def)
+	if f, _ := dt.FieldByName("WorkDir"); f.Tag.Get("yaml") != "workdir" {
+		t.Errorf("workdir field: got %+v", f)
+	}
+
+	if f := dt.Field(0); f.Tag.Get("description") != "Text." {
+		t.Errorf("description: got %q", f.Tag.Get("description"))
+	}
+
+	// decoded like the registry does it
+	params := reflect.New(dt)
+	params.Elem().Set(reflect.ValueOf(def))
+
+	data := "count: 5\nratio: 0.5\nitems: [a, 1]\noptions: {k: v}\nvalue: x\n"
+	if err := yaml.UnmarshalStrict([]byte(data), params.Interface()); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := paramsMap(params.Elem().Interface())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := json.Marshal(m)
+	want := `{"count":5,"enabled":true,"items":["a",1],"options":{"k":"v"},"ratio":0.5,"text":"hello","value":"x","workdir":""}`
+
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	if err := yaml.UnmarshalStrict([]byte("unknown: 1"), params.Interface()); err == nil {
+		t.Error("unknown param: expected an error")
+	}
+}
+
+func TestParamsStructErrors(t *testing.T) {
+	tests := []struct {
+		specs []ParamSpec
+		err   string
+	}{
+		{[]ParamSpec{{Name: "a", Type: "float"}}, "unknown type"},
+		{[]ParamSpec{{Name: "", Type: "string"}}, "invalid name"},
+		{[]ParamSpec{{Name: `a"b`, Type: "string"}}, "invalid name"},
+		{[]ParamSpec{{Name: "a", Type: "string"}, {Name: "a", Type: "integer"}}, "duplicate name"},
+		{[]ParamSpec{{Name: "workdir", Type: "integer"}}, "should be 'string'"},
+		{[]ParamSpec{{Name: "a", Type: "integer", Default: json.RawMessage(`"x"`)}}, "invalid default"},
+	}
+
+	for _, tt := range tests {
+		_, err := paramsStruct(tt.specs)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%+v: got error %v, want %q", tt.specs, err, tt.err)
+		}
+	}
+}
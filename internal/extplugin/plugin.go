@@ -0,0 +1,237 @@
+// Package extplugin implements executable plugins.
+// An executable plugin is a separate process speaking JSON-RPC 2.0 over stdio,
+// see plugins/PROTOCOL.md for the protocol description.
+package extplugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/burik666/yagostatus/ygs"
+)
+
+// ProtocolVersion is the version of the plugins protocol.
+const ProtocolVersion = 1
+
+const exitTimeout = 3 * time.Second
+
+// WidgetTypeSpec describes a widget type registered by the plugin.
+type WidgetTypeSpec struct {
+	Name   string      `json:"name"`
+	Params []ParamSpec `json:"params"`
+}
+
+type initializeParams struct {
+	ProtocolVersion int                        `json:"protocol_version"`
+	Params          map[string]json.RawMessage `json:"params"`
+}
+
+type initializeResult struct {
+	Widgets []WidgetTypeSpec `json:"widgets"`
+}
+
+type outputParams struct {
+	ID     uint64           `json:"id"`
+	Blocks []ygs.I3BarBlock `json:"blocks"`
+}
+
+type logParams struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+type plugin struct {
+	path    string
+	workdir string
+	params  map[string]interface{}
+
+	logger ygs.Logger
+	client *client
+
+	m        sync.RWMutex
+	widgets  map[uint64]*widget
+	nextID   uint64
+	registry []string
+}
+
+// Spec returns the spec of the executable plugin, the process is started by InitFunc.
+func Spec(path string, workdir string, params map[string]interface{}) ygs.PluginSpec {
+	p := &plugin{
+		path:    path,
+		workdir: workdir,
+		params:  params,
+		widgets: make(map[uint64]*widget),
+	}
+
+	return ygs.PluginSpec{
+		Name:       fmt.Sprintf("exec:%s", path),
+		APIVersion: ygs.APIVersion,
+		InitFunc: func(_ interface{}, l ygs.Logger) error {
+			return p.init(l)
+		},
+		ShutdownFunc: p.shutdown,
+	}
+}
+
+func (p *plugin) init(logger ygs.Logger) error {
+	p.logger = logger
+
+	c, err := startClient(p.path, nil, p.workdir, logger, p.handleNotification)
+	if err != nil {
+		return err
+	}
+
+	p.client = c
+
+	if err := p.initialize(); err != nil {
+		for _, name := range p.registry {
+			ygs.UnregisterWidget(name)
+		}
+
+		p.registry = nil
+		p.client = nil
+
+		// the plugin is not used, it is killed without waiting
+		if cerr := c.close(0); cerr != nil {
+			logger.Errorf("kill: %s", cerr)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// initialize sends the Initialize request and registers the widget types of the plugin.
+func (p *plugin) initialize() error {
+	params := make(map[string]json.RawMessage, len(p.params))
+
+	for k, v := range p.params {
+		b, err := jsonParam(v)
+		if err != nil {
+			return fmt.Errorf("param '%s': %w", k, err)
+		}
+
+		params[k] = b
+	}
+
+	var res initializeResult
+	if err := p.client.call("Initialize", initializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Params:          params,
+	}, &res); err != nil {
+		return fmt.Errorf("initialize: %w", err)
+	}
+
+	for _, wt := range res.Widgets {
+		def, err := paramsStruct(wt.Params)
+		if err != nil {
+			return fmt.Errorf("widget '%s': %w", wt.Name, err)
+		}
+
+		if err := ygs.RegisterWidget(ygs.WidgetSpec{
+			Name:          wt.Name,
+			DefaultParams: def,
+			NewFunc:       p.newWidgetFunc(wt.Name),
+		}); err != nil {
+			return err
+		}
+
+		p.registry = append(p.registry, wt.Name)
+	}
+
+	return nil
+}
+
+func (p *plugin) shutdown() error {
+	if p.client == nil {
+		return nil
+	}
+
+	for _, name := range p.registry {
+		ygs.UnregisterWidget(name)
+	}
+
+	if err := p.client.notify("Exit", nil); err != nil {
+		p.logger.Errorf("exit: %s", err)
+	}
+
+	return p.client.close(exitTimeout)
+}
+
+func (p *plugin) newWidgetFunc(name string) ygs.NewWidgetFunc {
+	return func(params interface{}, wlogger ygs.Logger) (ygs.Widget, error) {
+		pm, err := paramsMap(params)
+		if err != nil {
+			return nil, err
+		}
+
+		p.m.Lock()
+		p.nextID++
+		w := &widget{
+			id:     p.nextID,
+			plugin: p,
+			logger: wlogger,
+		}
+		p.widgets[w.id] = w
+		p.m.Unlock()
+
+		if err := p.client.call("NewWidget", map[string]interface{}{
+			"id":     w.id,
+			"widget": name,
+			"params": pm,
+		}, nil); err != nil {
+			p.m.Lock()
+			delete(p.widgets, w.id)
+			p.m.Unlock()
+
+			return nil, err
+		}
+
+		return w, nil
+	}
+}
+
+func (p *plugin) handleNotification(method string, params json.RawMessage) {
+	switch method {
+	case "Output":
+		var out outputParams
+		if err := json.Unmarshal(params, &out); err != nil {
+			p.logger.Errorf("invalid Output: %s", err)
+
+			return
+		}
+
+		p.m.RLock()
+		w, ok := p.widgets[out.ID]
+		p.m.RUnlock()
+
+		if !ok {
+			p.logger.Errorf("Output: unknown widget id %d", out.ID)
+
+			return
+		}
+
+		w.output(out.Blocks)
+	case "Log":
+		var l logParams
+		if err := json.Unmarshal(params, &l); err != nil {
+			p.logger.Errorf("invalid Log: %s", err)
+
+			return
+		}
+
+		switch l.Level {
+		case "error":
+			p.logger.Errorf("%s", l.Message)
+		case "debug":
+			p.logger.Debugf("%s", l.Message)
+		default:
+			p.logger.Infof("%s", l.Message)
+		}
+	default:
+		p.logger.Errorf("unknown notification: %s", method)
+	}
+}
@@ -0,0 +1,329 @@
+package extplugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/burik666/yagostatus/ygs"
+)
+
+const jsonrpcVersion = "2.0"
+
+// ErrExited is returned for requests when the plugin process is exited.
+var ErrExited = errors.New("plugin process exited")
+
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *uint64         `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// notificationHandler handles notifications sent by the plugin.
+type notificationHandler func(method string, params json.RawMessage)
+
+// client is a JSON-RPC 2.0 client over the stdio of the plugin process.
+// Messages are newline-delimited JSON objects.
+type client struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	logger ygs.Logger
+
+	handler notificationHandler
+
+	// notifications are queued and handled by notifyLoop,
+	// so a blocked handler does not stop reading the responses
+	nm       sync.Mutex
+	ncond    *sync.Cond
+	queue    []message
+	queueEnd bool
+
+	wm      sync.Mutex
+	m       sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan message
+	exited  chan struct{}
+}
+
+func startClient(path string, args []string, workdir string, logger ygs.Logger, handler notificationHandler) (*client, error) {
+	cmd := exec.Command(path, args...)
+	cmd.Dir = workdir
+	cmd.Env = os.Environ()
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid: true,
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	c := &client{
+		cmd:     cmd,
+		stdin:   stdin,
+		logger:  logger,
+		handler: handler,
+		pending: make(map[uint64]chan message),
+		exited:  make(chan struct{}),
+	}
+
+	c.ncond = sync.NewCond(&c.nm)
+
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			logger.Errorf("(stderr) %s", scanner.Text())
+		}
+	}()
+
+	go c.notifyLoop()
+	go c.readLoop(stdout)
+
+	return c, nil
+}
+
+func (c *client) readLoop(stdout io.Reader) {
+	defer func() {
+		if err := c.cmd.Wait(); err != nil {
+			c.logger.Errorf("plugin exited: %s", err)
+		}
+
+		c.nm.Lock()
+		c.queueEnd = true
+		c.ncond.Broadcast()
+		c.nm.Unlock()
+
+		c.m.Lock()
+		close(c.exited)
+
+		for id, ch := range c.pending {
+			close(ch)
+			delete(c.pending, id)
+		}
+		c.m.Unlock()
+	}()
+
+	decoder := json.NewDecoder(stdout)
+
+	for {
+		var msg message
+		if err := decoder.Decode(&msg); err != nil {
+			if !errors.Is(err, io.EOF) {
+				c.logger.Errorf("invalid message: %s", err)
+			}
+
+			return
+		}
+
+		if msg.Method != "" {
+			if msg.ID != nil {
+				c.reply(*msg.ID, nil, &rpcError{Code: -32601, Message: "method not found"})
+
+				continue
+			}
+
+			c.nm.Lock()
+			c.queue = append(c.queue, msg)
+			c.ncond.Signal()
+			c.nm.Unlock()
+
+			continue
+		}
+
+		if msg.ID == nil {
+			c.logger.Errorf("invalid message: missing id")
+
+			continue
+		}
+
+		c.m.Lock()
+		ch, ok := c.pending[*msg.ID]
+		delete(c.pending, *msg.ID)
+		c.m.Unlock()
+
+		if !ok {
+			c.logger.Errorf("unexpected response id: %d", *msg.ID)
+
+			continue
+		}
+
+		ch <- msg
+	}
+}
+
+// notifyLoop passes the queued notifications to the handler in order.
+func (c *client) notifyLoop() {
+	for {
+		c.nm.Lock()
+		for len(c.queue) == 0 && !c.queueEnd {
+			c.ncond.Wait()
+		}
+
+		if len(c.queue) == 0 {
+			c.nm.Unlock()
+
+			return
+		}
+
+		msg := c.queue[0]
+		c.queue[0] = message{}
+		c.queue = c.queue[1:]
+		c.nm.Unlock()
+
+		c.handler(msg.Method, msg.Params)
+	}
+}
+
+// call sends a request and waits for the response.
+func (c *client) call(method string, params interface{}, result interface{}) error {
+	ch := make(chan message, 1)
+
+	c.m.Lock()
+
+	select {
+	case <-c.exited:
+		c.m.Unlock()
+
+		return ErrExited
+	default:
+	}
+
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = ch
+	c.m.Unlock()
+
+	if err := c.send(&id, method, params); err != nil {
+		c.m.Lock()
+		delete(c.pending, id)
+		c.m.Unlock()
+
+		return err
+	}
+
+	msg, ok := <-ch
+	if !ok {
+		return ErrExited
+	}
+
+	if msg.Error != nil {
+		return msg.Error
+	}
+
+	if result != nil && len(msg.Result) > 0 {
+		return json.Unmarshal(msg.Result, result)
+	}
+
+	return nil
+}
+
+// notify sends a notification.
+func (c *client) notify(method string, params interface{}) error {
+	return c.send(nil, method, params)
+}
+
+func (c *client) send(id *uint64, method string, params interface{}) error {
+	msg := message{
+		JSONRPC: jsonrpcVersion,
+		ID:      id,
+		Method:  method,
+	}
+
+	if params != nil {
+		p, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+
+		msg.Params = p
+	}
+
+	return c.write(msg)
+}
+
+func (c *client) reply(id uint64, result interface{}, rerr *rpcError) {
+	msg := message{
+		JSONRPC: jsonrpcVersion,
+		ID:      &id,
+		Error:   rerr,
+	}
+
+	if result != nil {
+		msg.Result, _ = json.Marshal(result)
+	}
+
+	if err := c.write(msg); err != nil {
+		c.logger.Errorf("failed to reply: %s", err)
+	}
+}
+
+func (c *client) write(msg message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	b = append(b, '\n')
+
+	c.wm.Lock()
+	defer c.wm.Unlock()
+
+	_, err = c.stdin.Write(b)
+
+	return err
+}
+
+// close closes stdin of the plugin and kills it after the timeout.
+func (c *client) close(timeout time.Duration) error {
+	_ = c.stdin.Close()
+
+	select {
+	case <-c.exited:
+		return nil
+	case <-time.After(timeout):
+	}
+
+	if c.cmd.Process != nil {
+		// the process may exit after the timeout
+		if err := syscall.Kill(-c.cmd.Process.Pid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+			return err
+		}
+	}
+
+	<-c.exited
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package extplugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/burik666/yagostatus/ygs"
+)
+
+// stubEnv selects the behaviour of the stub plugin, the test binary is run as the plugin.
+const stubEnv = "YGS_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(stubEnv); mode != "" {
+		stubPlugin(mode)
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// stubPlugin replies to Initialize with a widget type, to Echo with the params,
+// to Fail with an error and sends a Log notification for Notify.
+func stubPlugin(mode string) {
+	out := json.NewEncoder(os.Stdout)
+	scanner := bufio.NewScanner(os.Stdin)
+
+	for scanner.Scan() {
+		var msg message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		if msg.ID == nil {
+			continue
+		}
+
+		res := message{JSONRPC: jsonrpcVersion, ID: msg.ID}
+
+		switch msg.Method {
+		case "Initialize":
+			res.Result = json.RawMessage(`{"widgets": [{"name": "stub_widget", "params": [{"name": "text", "type": "string"}]}]}`)
+			if mode == "duplicate" {
+				res.Result = json.RawMessage(`{"widgets": [{"name": "stub_dup", "params": [{"name": "a", "type": "string"}, {"name": "a", "type": "string"}]}]}`)
+			}
+		case "Echo":
+			res.Result = msg.Params
+		case "Fail":
+			res.Error = &rpcError{Code: 1, Message: "failed"}
+		case "Notify":
+			_ = out.Encode(message{JSONRPC: jsonrpcVersion, Method: "Log", Params: json.RawMessage(`{"message": "hello"}`)})
+			res.Result = json.RawMessage(`true`)
+		default:
+			res.Error = &rpcError{Code: -32601, Message: "method not found"}
+		}
+
+		_ = out.Encode(res)
+	}
+
+	// the plugin does not exit when stdin is closed, it should be killed
+	if mode == "duplicate" {
+		time.Sleep(time.Minute)
+	}
+}
+
+type testLogger struct{}
+
+func (l *testLogger) Infof(format string, v ...interface{})  {}
+func (l *testLogger) Errorf(format string, v ...interface{}) {}
+func (l *testLogger) Debugf(format string, v ...interface{}) {}
+
+func (l *testLogger) WithPrefix(prefix string) ygs.Logger {
+	return l
+}
+
+func TestClient(t *testing.T) {
+	t.Setenv(stubEnv, "ok")
+
+	notifications := make(chan string, 1)
+
+	c, err := startClient(os.Args[0], nil, "", &testLogger{}, func(method string, params json.RawMessage) {
+		notifications <- method + " " + string(params)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var res map[string]int
+	if err := c.call("Echo", map[string]int{"a": 1}, &res); err != nil {
+		t.Fatal(err)
+	}
+
+	if res["a"] != 1 {
+		t.Errorf("Echo: got %v", res)
+	}
+
+	var rerr *rpcError
+	if err := c.call("Fail", nil, nil); !errors.As(err, &rerr) || rerr.Code != 1 {
+		t.Errorf("Fail: got %v", err)
+	}
+
+	if err := c.call("Notify", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case n := <-notifications:
+		if n != `Log {"message":"hello"}` {
+			t.Errorf("notification: got %s", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("notification: timeout")
+	}
+
+	if err := c.close(exitTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.call("Echo", nil, nil); !errors.Is(err, ErrExited) {
+		t.Errorf("after close: got %v, want %v", err, ErrExited)
+	}
+}
+
+func registered(name string) bool {
+	for _, w := range ygs.RegisteredWidgets() {
+		if w.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestPluginInit(t *testing.T) {
+	t.Setenv(stubEnv, "ok")
+
+	spec := Spec(os.Args[0], "", map[string]interface{}{"key": "value"})
+
+	if err := spec.InitFunc(nil, &testLogger{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !registered("stub_widget") {
+		t.Error("stub_widget is not registered")
+	}
+
+	if err := spec.ShutdownFunc(); err != nil {
+		t.Fatal(err)
+	}
+
+	if registered("stub_widget") {
+		t.Error("stub_widget is registered after shutdown")
+	}
+}
+
+func TestPluginInitFailure(t *testing.T) {
+	t.Setenv(stubEnv, "duplicate")
+
+	spec := Spec(os.Args[0], "", nil)
+
+	started := time.Now()
+
+	err := spec.InitFunc(nil, &testLogger{})
+	if err == nil || !strings.Contains(err.Error(), "duplicate name") {
+		t.Fatalf("got error %v, want duplicate name", err)
+	}
+
+	// init waits for the killed process, the stub does not exit by itself
+	if d := time.Since(started); d >= exitTimeout {
+		t.Errorf("the plugin process was not killed (%s)", d)
+	}
+
+	if registered("stub_dup") {
+		t.Error("stub_dup is registered")
+	}
+
+	if err := spec.ShutdownFunc(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,80 @@
+package extplugin
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/burik666/yagostatus/ygs"
+)
+
+// widget is a widget implemented by an executable plugin.
+type widget struct {
+	id     uint64
+	plugin *plugin
+	logger ygs.Logger
+
+	m sync.RWMutex
+	c chan<- []ygs.I3BarBlock
+}
+
+type widgetParams struct {
+	ID uint64 `json:"id"`
+}
+
+type eventParams struct {
+	ID     uint64              `json:"id"`
+	Event  ygs.I3BarClickEvent `json:"event"`
+	Blocks []ygs.I3BarBlock    `json:"blocks"`
+}
+
+// Run starts the widget, the plugin replies when the widget is done.
+func (w *widget) Run(c chan<- []ygs.I3BarBlock) error {
+	w.m.Lock()
+	w.c = c
+	w.m.Unlock()
+
+	return w.plugin.client.call("Run", widgetParams{ID: w.id}, nil)
+}
+
+// Event processes the widget events.
+func (w *widget) Event(event ygs.I3BarClickEvent, blocks []ygs.I3BarBlock) error {
+	return w.plugin.client.call("Event", eventParams{
+		ID:     w.id,
+		Event:  event,
+		Blocks: blocks,
+	}, nil)
+}
+
+// Stop stops the widget.
+func (w *widget) Stop() error {
+	return w.plugin.client.call("Stop", widgetParams{ID: w.id}, nil)
+}
+
+// Continue continues the widget.
+func (w *widget) Continue() error {
+	return w.plugin.client.call("Continue", widgetParams{ID: w.id}, nil)
+}
+
+// Shutdown shutdowns the widget.
+func (w *widget) Shutdown() error {
+	err := w.plugin.client.call("Shutdown", widgetParams{ID: w.id}, nil)
+	if errors.Is(err, ErrExited) {
+		return nil
+	}
+
+	return err
+}
+
+func (w *widget) output(blocks []ygs.I3BarBlock) {
+	w.m.RLock()
+	c := w.c
+	w.m.RUnlock()
+
+	if c == nil {
+		w.logger.Errorf("output before Run is ignored")
+
+		return
+	}
+
+	c <- blocks
+}
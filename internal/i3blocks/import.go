@@ -0,0 +1,325 @@
+// Package i3blocks converts i3blocks configs into yagostatus configs.
+package i3blocks
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// block is a section of the i3blocks config.
+type block struct {
+	name  string
+	line  int
+	props map[string]string
+	// keys in order of appearance
+	keys []string
+}
+
+func (b *block) set(key, value string) {
+	if _, ok := b.props[key]; !ok {
+		b.keys = append(b.keys, key)
+	}
+
+	b.props[key] = value
+}
+
+// widget is the converted widget, fields are in the order of the output.
+type widget struct {
+	Widget       string                   `yaml:"widget"`
+	Command      string                   `yaml:"command,omitempty"`
+	OutputFormat string                   `yaml:"output_format,omitempty"`
+	Interval     int                      `yaml:"interval,omitempty"`
+	Signal       *int                     `yaml:"signal,omitempty"`
+	EventsUpdate bool                     `yaml:"events_update,omitempty"`
+	Label        string                   `yaml:"label,omitempty"`
+	Env          []string                 `yaml:"env,omitempty"`
+	Blocks       []map[string]interface{} `yaml:"blocks,omitempty"`
+	Templates    []map[string]interface{} `yaml:"templates,omitempty"`
+}
+
+// blockProps are the i3bar block properties, they are copied to templates or static blocks.
+var blockProps = map[string]func(string) (interface{}, error){
+	"full_text":             stringValue,
+	"short_text":            stringValue,
+	"color":                 stringValue,
+	"background":            stringValue,
+	"border":                stringValue,
+	"border_top":            intValue,
+	"border_bottom":         intValue,
+	"border_left":           intValue,
+	"border_right":          intValue,
+	"min_width":             minWidthValue,
+	"align":                 stringValue,
+	"urgent":                boolValue,
+	"separator":             boolValue,
+	"separator_block_width": intValue,
+	"markup":                stringValue,
+}
+
+// Import converts the i3blocks config into the yagostatus config.
+// Properties that can not be translated are returned as warnings and written as comments.
+func Import(r io.Reader, file string) ([]byte, []string, error) {
+	blocks, lineWarnings, err := parse(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	warnings := make([]string, 0, len(lineWarnings))
+	for _, warn := range lineWarnings {
+		warnings = append(warnings, fmt.Sprintf("%s:%s", file, warn))
+	}
+
+	widgets := &yaml.Node{Kind: yaml.SequenceNode}
+
+	for _, b := range blocks {
+		w, bwarnings := convert(b)
+
+		for _, warn := range bwarnings {
+			warnings = append(warnings, fmt.Sprintf("%s:%d: [%s] %s", file, b.line, b.name, warn))
+		}
+
+		if w == nil {
+			continue
+		}
+
+		node := &yaml.Node{}
+		if err := node.Encode(w); err != nil {
+			return nil, nil, err
+		}
+
+		node.HeadComment = fmt.Sprintf("[%s]", b.name)
+		for _, warn := range bwarnings {
+			node.HeadComment += "\nWARNING: " + warn
+		}
+
+		widgets.Content = append(widgets.Content, node)
+	}
+
+	doc := &yaml.Node{
+		Kind:        yaml.MappingNode,
+		HeadComment: fmt.Sprintf("converted from %s by yagostatus import i3blocks", file),
+		Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "widgets"},
+			widgets,
+		},
+	}
+
+	var buf bytes.Buffer
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(doc); err != nil {
+		return nil, nil, err
+	}
+
+	if err := enc.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	return buf.Bytes(), warnings, nil
+}
+
+// parse reads the INI-style config, global properties are applied to all blocks.
+func parse(r io.Reader) ([]*block, []string, error) {
+	var (
+		blocks   []*block
+		warnings []string
+	)
+
+	global := &block{props: make(map[string]string)}
+	cur := global
+
+	scanner := bufio.NewScanner(r)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			cur = &block{
+				name:  strings.TrimSpace(line[1 : len(line)-1]),
+				line:  n,
+				props: make(map[string]string),
+			}
+
+			for _, k := range global.keys {
+				cur.set(k, global.props[k])
+			}
+
+			blocks = append(blocks, cur)
+		default:
+			i := strings.Index(line, "=")
+			if i <= 0 {
+				warnings = append(warnings, fmt.Sprintf("%d: invalid line '%s'", n, line))
+
+				continue
+			}
+
+			cur.set(strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return blocks, warnings, nil
+}
+
+// convert returns the widget of the block, nil if the block can not be converted.
+func convert(b *block) (*widget, []string) {
+	var warnings []string
+
+	w := &widget{Widget: "exec"}
+	tpl := make(map[string]interface{})
+	name := b.name
+
+	for _, k := range b.keys {
+		v := b.props[k]
+
+		if conv, ok := blockProps[k]; ok {
+			val, err := conv(escapeVars(v))
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("%s: %s", k, err))
+
+				continue
+			}
+
+			tpl[k] = val
+
+			continue
+		}
+
+		switch k {
+		case "command":
+			// shell variables are not expanded by yagostatus
+			w.Command = escapeVars(v)
+		case "instance":
+			w.Env = append(w.Env, "BLOCK_INSTANCE="+escapeVars(v))
+		case "label":
+			w.Label = escapeVars(v)
+		case "name":
+			name = v
+		case "interval", "format", "signal":
+			// converted below
+		default:
+			// blocklets read custom properties from the environment
+			w.Env = append(w.Env, k+"="+escapeVars(v))
+		}
+	}
+
+	if name != "" {
+		w.Env = append([]string{"BLOCK_NAME=" + escapeVars(name)}, w.Env...)
+	}
+
+	if w.Command == "" {
+		if _, ok := tpl["full_text"]; !ok {
+			return nil, append(warnings, "no command or full_text, skipped")
+		}
+
+		for _, k := range []string{"interval", "signal", "format", "label"} {
+			if _, ok := b.props[k]; ok {
+				warnings = append(warnings, fmt.Sprintf("%s: ignored without command", k))
+			}
+		}
+
+		return &widget{
+			Widget: "static",
+			Blocks: []map[string]interface{}{tpl},
+		}, warnings
+	}
+
+	isJSON := b.props["format"] == "json"
+	if f, ok := b.props["format"]; ok && f != "json" {
+		warnings = append(warnings, fmt.Sprintf("format: unknown format '%s'", f))
+	}
+
+	w.OutputFormat = "i3blocks"
+	if isJSON {
+		w.OutputFormat = "json-lines"
+		w.EventsUpdate = true
+
+		warnings = append(warnings, "format: BLOCK_* click variables are not passed to json blocklets")
+	}
+
+	switch interval := b.props["interval"]; interval {
+	case "", "once":
+	case "repeat":
+		w.Interval = -1
+	case "persist":
+		if !isJSON {
+			w.OutputFormat = "text-stream"
+		}
+
+		w.EventsUpdate = false
+
+		warnings = append(warnings, "interval: clicks are not written to stdin of persistent blocklets")
+	default:
+		n, err := strconv.Atoi(interval)
+		if err != nil || n < 0 {
+			warnings = append(warnings, fmt.Sprintf("interval: invalid value '%s'", interval))
+		} else {
+			w.Interval = n
+		}
+	}
+
+	if s, ok := b.props["signal"]; ok {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			warnings = append(warnings, fmt.Sprintf("signal: invalid value '%s'", s))
+		} else {
+			w.Signal = &n
+		}
+	}
+
+	if len(tpl) > 0 {
+		w.Templates = []map[string]interface{}{tpl}
+	}
+
+	return w, warnings
+}
+
+// escapeVars escapes ${ as $${, so the values are not expanded as yagostatus variables.
+func escapeVars(s string) string {
+	return strings.ReplaceAll(s, "${", "$${")
+}
+
+func stringValue(s string) (interface{}, error) {
+	return s, nil
+}
+
+func intValue(s string) (interface{}, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number '%s'", s)
+	}
+
+	return n, nil
+}
+
+func boolValue(s string) (interface{}, error) {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid boolean '%s'", s)
+	}
+
+	return b, nil
+}
+
+// minWidthValue is a number of pixels or a string.
+func minWidthValue(s string) (interface{}, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, nil
+	}
+
+	return s, nil
+}
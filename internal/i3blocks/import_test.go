@@ -0,0 +1,164 @@
+package i3blocks
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const header = "# converted from cfg by yagostatus import i3blocks\nwidgets:\n"
+
+func TestImport(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		out      string
+		warnings []string
+	}{
+		{
+			name: "global properties",
+			in:   "command=/bin/global\ninterval=5\n[a]\ninstance=x\nsignal=3\ncolor=#ff0000\n",
+			out: `  # [a]
+  - widget: exec
+    command: /bin/global
+    output_format: i3blocks
+    interval: 5
+    signal: 3
+    env:
+      - BLOCK_NAME=a
+      - BLOCK_INSTANCE=x
+    templates:
+      - color: '#ff0000'
+`,
+		},
+		{
+			name: "interval once",
+			in:   "[a]\ncommand=c\ninterval=once\n",
+			out: `  # [a]
+  - widget: exec
+    command: c
+    output_format: i3blocks
+    env:
+      - BLOCK_NAME=a
+`,
+		},
+		{
+			name: "interval repeat",
+			in:   "[a]\ncommand=c\ninterval=repeat\n",
+			out: `  # [a]
+  - widget: exec
+    command: c
+    output_format: i3blocks
+    interval: -1
+    env:
+      - BLOCK_NAME=a
+`,
+		},
+		{
+			name: "interval persist",
+			in:   "[a]\ncommand=c\ninterval=persist\n",
+			out: `  # [a]
+  # WARNING: interval: clicks are not written to stdin of persistent blocklets
+  - widget: exec
+    command: c
+    output_format: text-stream
+    env:
+      - BLOCK_NAME=a
+`,
+			warnings: []string{"cfg:1: [a] interval: clicks are not written to stdin of persistent blocklets"},
+		},
+		{
+			name: "format json",
+			in:   "[a]\ncommand=c\nformat=json\n",
+			out: `  # [a]
+  # WARNING: format: BLOCK_* click variables are not passed to json blocklets
+  - widget: exec
+    command: c
+    output_format: json-lines
+    events_update: true
+    env:
+      - BLOCK_NAME=a
+`,
+			warnings: []string{"cfg:1: [a] format: BLOCK_* click variables are not passed to json blocklets"},
+		},
+		{
+			name: "label, name and custom properties",
+			in:   "[a]\nname=n\nlabel=L:\nfoo=bar\ncommand=c\n",
+			out: `  # [a]
+  - widget: exec
+    command: c
+    output_format: i3blocks
+    label: 'L:'
+    env:
+      - BLOCK_NAME=n
+      - foo=bar
+`,
+		},
+		{
+			name: "static blocks",
+			in:   "[static]\nfull_text=hello\nseparator=false\n[none]\nlabel=x\n",
+			out: `  # [static]
+  - widget: static
+    blocks:
+      - full_text: hello
+        separator: false
+`,
+			warnings: []string{"cfg:4: [none] no command or full_text, skipped"},
+		},
+		{
+			name: "invalid values",
+			in:   "junk\n[a]\ncommand=c\ninterval=abc\nborder_top=x\nsignal=-1\nformat=yaml\n",
+			out: `  # [a]
+  # WARNING: border_top: invalid number 'x'
+  # WARNING: format: unknown format 'yaml'
+  # WARNING: interval: invalid value 'abc'
+  # WARNING: signal: invalid value '-1'
+  - widget: exec
+    command: c
+    output_format: i3blocks
+    env:
+      - BLOCK_NAME=a
+`,
+			warnings: []string{
+				"cfg:1: invalid line 'junk'",
+				"cfg:2: [a] border_top: invalid number 'x'",
+				"cfg:2: [a] format: unknown format 'yaml'",
+				"cfg:2: [a] interval: invalid value 'abc'",
+				"cfg:2: [a] signal: invalid value '-1'",
+			},
+		},
+		{
+			name: "variables escaping",
+			in:   "[a]\ncommand=echo ${HOME:-/tmp} $${x}\nfoo=${bar}\n",
+			out: `  # [a]
+  - widget: exec
+    command: echo $${HOME:-/tmp} $$${x}
+    output_format: i3blocks
+    env:
+      - BLOCK_NAME=a
+      - foo=$${bar}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, warnings, err := Import(strings.NewReader(tt.in), "cfg")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(out) != header+tt.out {
+				t.Errorf("got:\n%s\nwant:\n%s", out, header+tt.out)
+			}
+
+			if len(warnings) == 0 {
+				warnings = nil
+			}
+
+			if !reflect.DeepEqual(warnings, tt.warnings) {
+				t.Errorf("got warnings %q, want %q", warnings, tt.warnings)
+			}
+		})
+	}
+}
@@ -14,46 +14,113 @@ import (
 
 // NewWidget creates new widget by name.
 func NewWidget(widgetConfig config.WidgetConfig, wlogger ygs.Logger) (ygs.Widget, error) {
+	widget, params, err := DecodeParams(widgetConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return widget.NewFunc(params, wlogger)
+}
+
+// DecodeParams finds the widget by name and decodes its parameters.
+func DecodeParams(widgetConfig config.WidgetConfig) (ygs.WidgetSpec, interface{}, error) {
 	name := widgetConfig.Name
 	wi, ok := rs.Load("widget_" + name)
 
 	if !ok {
-		return nil, fmt.Errorf("widget '%s' not found", name)
+		return ygs.WidgetSpec{}, nil, fmt.Errorf("widget '%s' not found", name)
 	}
 
 	widget := wi.(ygs.WidgetSpec)
 	if widget.DefaultParams == nil {
-		return widget.NewFunc(nil, wlogger)
+		return widget, nil, nil
 	}
 
-	def := reflect.ValueOf(widget.DefaultParams)
-
-	params := reflect.New(def.Type())
-	pe := params.Elem()
-	pe.Set(def)
-
 	delete(widgetConfig.Params, "template")
 	delete(widgetConfig.Params, "templates")
 
-	b, err := yaml.Marshal(widgetConfig.Params)
+	params, err := decodeParams(widget.DefaultParams, widgetConfig.Params)
+	if err != nil {
+		return widget, nil, err
+	}
+
+	_, hasWorkDir := widgetConfig.Params["workdir"]
+
+	for i := 0; i < params.NumField(); i++ {
+		fn := params.Type().Field(i).Name
+		if strings.ToLower(fn) == "workdir" && !hasWorkDir {
+			params.Field(i).SetString(widgetConfig.WorkDir)
+		}
+
+		// the widget id is available for widgets with the WidgetID field (yaml:"-")
+		if fn == "WidgetID" {
+			params.Field(i).SetString(widgetConfig.ID)
+		}
+	}
+
+	return widget, params.Interface(), nil
+}
+
+// NewFilter creates new output filter by name.
+func NewFilter(filterConfig config.FilterConfig, flogger ygs.Logger) (ygs.Filter, error) {
+	filter, params, err := DecodeFilterParams(filterConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := yaml.UnmarshalStrict(b, params.Interface()); err != nil {
-		return nil, trimYamlErr(err, true)
+	return filter.NewFunc(params, flogger)
+}
+
+// DecodeFilterParams finds the filter by name and decodes its parameters.
+func DecodeFilterParams(filterConfig config.FilterConfig) (ygs.FilterSpec, interface{}, error) {
+	name := filterConfig.Name
+	fi, ok := rs.Load("filter_" + name)
+
+	if !ok {
+		return ygs.FilterSpec{}, nil, fmt.Errorf("filter '%s' not found", name)
 	}
 
-	if _, ok := widgetConfig.Params["workdir"]; !ok {
-		for i := 0; i < pe.NumField(); i++ {
-			fn := pe.Type().Field(i).Name
-			if strings.ToLower(fn) == "workdir" {
-				pe.Field(i).SetString(widgetConfig.WorkDir)
-			}
-		}
+	filter := fi.(ygs.FilterSpec)
+	if filter.DefaultParams == nil {
+		return filter, nil, nil
+	}
+
+	params, err := decodeParams(filter.DefaultParams, filterConfig.Params)
+	if err != nil {
+		return filter, nil, err
+	}
+
+	return filter, params.Interface(), nil
+}
+
+// Action finds the event action by name.
+func Action(name string) (ygs.ActionSpec, bool) {
+	a, ok := rs.Load("action_" + name)
+	if !ok {
+		return ygs.ActionSpec{}, false
+	}
+
+	return a.(ygs.ActionSpec), true
+}
+
+// decodeParams decodes parameters into a copy of the default parameters struct.
+func decodeParams(defaultParams interface{}, cfgParams map[string]interface{}) (reflect.Value, error) {
+	def := reflect.ValueOf(defaultParams)
+
+	params := reflect.New(def.Type())
+	pe := params.Elem()
+	pe.Set(def)
+
+	b, err := yaml.Marshal(cfgParams)
+	if err != nil {
+		return pe, err
+	}
+
+	if err := yaml.UnmarshalStrict(b, params.Interface()); err != nil {
+		return pe, trimYamlErr(err, true)
 	}
 
-	return widget.NewFunc(pe.Interface(), wlogger)
+	return pe, nil
 }
 
 func trimYamlErr(err error, trimLineN bool) error {
@@ -0,0 +1,152 @@
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/burik666/yagostatus/ygs"
+)
+
+// placeholderRe matches {{name.path}} placeholders.
+var placeholderRe = regexp.MustCompile(`{{\s*([A-Za-z0-9_-]+)((?:\.[^.{}\s]+)*)\s*}}`)
+
+// References returns the names of sources referenced by placeholders in s.
+func References(s string) []string {
+	var names []string
+
+	seen := make(map[string]struct{})
+
+	for _, m := range placeholderRe.FindAllStringSubmatch(s, -1) {
+		if _, ok := seen[m[1]]; ok {
+			continue
+		}
+
+		seen[m[1]] = struct{}{}
+		names = append(names, m[1])
+	}
+
+	return names
+}
+
+// Lookup returns the value of the source by path (name.key.0.key).
+func Lookup(path string) (interface{}, bool) {
+	parts := strings.Split(path, ".")
+
+	s, ok := Get(parts[0])
+	if !ok {
+		return nil, false
+	}
+
+	v, ok := s.Value()
+	if !ok {
+		return nil, false
+	}
+
+	for _, p := range parts[1:] {
+		switch vv := v.(type) {
+		case map[string]interface{}:
+			if v, ok = vv[p]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(p)
+			if err != nil || i < 0 || i >= len(vv) {
+				return nil, false
+			}
+
+			v = vv[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+// Render replaces placeholders in s with the source values,
+// strings are inserted as is, other values as JSON, unknown values as empty strings.
+// Placeholders of unknown sources are kept as is.
+func Render(s string) string {
+	return placeholderRe.ReplaceAllStringFunc(s, func(ph string) string {
+		m := placeholderRe.FindStringSubmatch(ph)
+
+		if _, ok := Get(m[1]); !ok {
+			return ph
+		}
+
+		v, ok := Lookup(m[1] + m[2])
+		if !ok || v == nil {
+			return ""
+		}
+
+		if str, ok := v.(string); ok {
+			return str
+		}
+
+		b, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+
+		return string(b)
+	})
+}
+
+// RenderBlock renders placeholders in the string fields of the block.
+func RenderBlock(block ygs.I3BarBlock) (ygs.I3BarBlock, error) {
+	b, err := json.Marshal(block)
+	if err != nil {
+		return block, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return block, err
+	}
+
+	for k, v := range fields {
+		if s, ok := v.(string); ok {
+			fields[k] = Render(s)
+		}
+	}
+
+	b, err = json.Marshal(fields)
+	if err != nil {
+		return block, err
+	}
+
+	var res ygs.I3BarBlock
+	if err := json.Unmarshal(b, &res); err != nil {
+		return block, fmt.Errorf("render: %w", err)
+	}
+
+	return res, nil
+}
+
+// Declared returns the names of registered sources referenced by the blocks,
+// other placeholders are plain text.
+func Declared(blocks []ygs.I3BarBlock) []string {
+	var names []string
+
+	for _, name := range BlocksReferences(blocks) {
+		if _, ok := Get(name); ok {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// BlocksReferences returns the names of sources referenced by the blocks.
+func BlocksReferences(blocks []ygs.I3BarBlock) []string {
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	b, _ := json.Marshal(blocks)
+
+	return References(string(b))
+}
@@ -0,0 +1,304 @@
+// Package source implements shared data sources.
+// A source runs a command and caches its output, widgets render the cached data
+// with {{name.path}} placeholders and are updated when the source is refreshed.
+package source
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/burik666/yagostatus/pkg/schedule"
+	"github.com/burik666/yagostatus/pkg/signals"
+	"github.com/burik666/yagostatus/ygs"
+)
+
+// Params are source parameters.
+type Params struct {
+	Command  string            `yaml:"command" description:"Command to execute (via sh -c), the output is parsed as JSON (or used as text)."`
+	Interval schedule.Duration `yaml:"interval,omitempty" description:"Update interval in seconds or as a duration string like 500ms or 1h30m (0 to run once)."`
+	Align    bool              `yaml:"align,omitempty" description:"Align the interval to the wall clock (e.g. 60 runs at :00 of each minute)."`
+	Schedule string            `yaml:"schedule,omitempty" description:"Cron expression (minute hour day-of-month month day-of-week) or @hourly, @daily, etc. instead of interval."`
+	Signal   *int              `yaml:"signal,omitempty" description:"SIGRTMIN offset to update the source."`
+	Timeout  schedule.Duration `yaml:"timeout,omitempty" description:"Kill the command if it runs longer (default: 1m)."`
+	WorkDir  string            `yaml:"workdir,omitempty" description:"Working directory."`
+	Env      []string          `yaml:"env,omitempty" description:"Environment variables."`
+}
+
+// defaultTimeout is the command timeout if it is not set.
+const defaultTimeout = time.Minute
+
+// Source runs the command and caches its output.
+type Source struct {
+	name     string
+	params   Params
+	schedule schedule.Schedule
+	logger   ygs.Logger
+
+	upd  chan struct{}
+	done chan struct{}
+
+	m    sync.RWMutex
+	data interface{}
+	ok   bool
+	subs []chan struct{}
+}
+
+var (
+	sources = make(map[string]*Source)
+	sm      sync.RWMutex
+)
+
+// Register registers the source, it is started by Start.
+func Register(name string, params Params, logger ygs.Logger) error {
+	if params.Command == "" {
+		return fmt.Errorf("source '%s': missing 'command'", name)
+	}
+
+	if params.Interval < 0 {
+		return fmt.Errorf("source '%s': interval should be positive", name)
+	}
+
+	if params.Timeout < 0 {
+		return fmt.Errorf("source '%s': timeout should be positive", name)
+	}
+
+	if params.Timeout == 0 {
+		params.Timeout = schedule.Duration(defaultTimeout)
+	}
+
+	if params.Align && params.Interval == 0 {
+		return fmt.Errorf("source '%s': 'align' requires a positive 'interval'", name)
+	}
+
+	var sched schedule.Schedule
+
+	switch {
+	case params.Schedule != "" && params.Interval != 0:
+		return fmt.Errorf("source '%s': 'schedule' and 'interval' are mutually exclusive", name)
+	case params.Schedule != "":
+		cron, err := schedule.ParseCron(params.Schedule)
+		if err != nil {
+			return fmt.Errorf("source '%s': schedule: %w", name, err)
+		}
+
+		sched = cron
+	case params.Interval > 0:
+		sched = schedule.Every{
+			Interval: time.Duration(params.Interval),
+			Align:    params.Align,
+		}
+	}
+
+	if params.Signal != nil {
+		sig := *params.Signal
+		if sig < 0 || signals.SIGRTMIN+sig > signals.SIGRTMAX {
+			return fmt.Errorf("source '%s': signal should be between 0 AND %d", name, signals.SIGRTMAX-signals.SIGRTMIN)
+		}
+	}
+
+	sm.Lock()
+	defer sm.Unlock()
+
+	if _, ok := sources[name]; ok {
+		return fmt.Errorf("source '%s' already registered", name)
+	}
+
+	sources[name] = &Source{
+		name:     name,
+		params:   params,
+		schedule: sched,
+		logger:   logger,
+		upd:      make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+
+	return nil
+}
+
+// Get returns the source by name.
+func Get(name string) (*Source, bool) {
+	sm.RLock()
+	defer sm.RUnlock()
+
+	s, ok := sources[name]
+
+	return s, ok
+}
+
+// Names returns the names of registered sources.
+func Names() []string {
+	sm.RLock()
+	defer sm.RUnlock()
+
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Start starts all registered sources.
+func Start() {
+	sm.RLock()
+	defer sm.RUnlock()
+
+	for _, s := range sources {
+		go s.run()
+	}
+}
+
+// Shutdown stops all registered sources.
+func Shutdown() {
+	sm.Lock()
+	defer sm.Unlock()
+
+	for name, s := range sources {
+		close(s.done)
+		delete(sources, name)
+	}
+}
+
+// Value returns the cached output, ok is false if the command has not succeeded yet.
+func (s *Source) Value() (interface{}, bool) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	return s.data, s.ok
+}
+
+// Subscribe returns a channel that receives a value when the source is updated.
+func (s *Source) Subscribe() <-chan struct{} {
+	c := make(chan struct{}, 1)
+
+	s.m.Lock()
+	s.subs = append(s.subs, c)
+	s.m.Unlock()
+
+	return c
+}
+
+// Refresh runs the command again.
+func (s *Source) Refresh() {
+	select {
+	case s.upd <- struct{}{}:
+	default:
+		// an update is already pending
+	}
+}
+
+func (s *Source) run() {
+	s.Refresh()
+
+	if s.schedule != nil {
+		go func() {
+			ticker := schedule.NewTicker(s.schedule)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-s.done:
+					return
+				case <-ticker.C:
+					s.Refresh()
+				}
+			}
+		}()
+	}
+
+	if s.params.Signal != nil {
+		sigc := make(chan os.Signal, 1)
+		signal.Notify(sigc, syscall.Signal(signals.SIGRTMIN+*s.params.Signal))
+
+		go func() {
+			defer signal.Stop(sigc)
+
+			for {
+				select {
+				case <-s.done:
+					return
+				case <-sigc:
+					s.Refresh()
+				}
+			}
+		}()
+	}
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.upd:
+			if err := s.update(); err != nil {
+				s.logger.Errorf("update failed: %s", err)
+			}
+		}
+	}
+}
+
+func (s *Source) update() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.params.Timeout))
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", s.params.Command)
+	cmd.Dir = s.params.WorkDir
+	cmd.Env = append(os.Environ(), s.params.Env...)
+	// children of the shell are killed too
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return fmt.Errorf("timeout (%s)", s.params.Timeout)
+	}
+
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+
+		return err
+	}
+
+	var data interface{} = strings.TrimRight(string(out), "\n")
+
+	if json.Valid(out) {
+		decoder := json.NewDecoder(bytes.NewReader(out))
+		decoder.UseNumber()
+
+		if err := decoder.Decode(&data); err != nil {
+			return err
+		}
+	}
+
+	s.m.Lock()
+	s.data = data
+	s.ok = true
+	subs := s.subs
+	s.m.Unlock()
+
+	for _, c := range subs {
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
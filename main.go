@@ -10,6 +10,7 @@ import (
 
 	"github.com/burik666/yagostatus/internal/config"
 	"github.com/burik666/yagostatus/internal/logger"
+	"github.com/burik666/yagostatus/internal/registry"
 )
 
 var builtinConfig = []byte(`
@@ -47,6 +48,10 @@ func main() {
 	versionFlag := flag.Bool("version", false, "print version information and exit")
 	swayFlag := flag.Bool("sway", false, "set it when using sway")
 	dumpConfigFlag := flag.Bool("dump", false, "dump parsed config file to stdout")
+	resolvedFlag := flag.Bool("resolved", false, "use with -dump to dump widgets merged with default parameters and annotated with their origin")
+	schemaFlag := flag.Bool("schema", false, "print JSON Schema of the config (including loaded plugins) to stdout")
+	checkFlag := flag.Bool("check", false, "check config file (-check [file]) and print all errors")
+	pluginInfo := flag.String("plugin-info", "", "print information about the go plugin file and check its compatibility")
 
 	flag.Parse()
 
@@ -56,6 +61,29 @@ func main() {
 		return
 	}
 
+	if flag.Arg(0) == "import" {
+		os.Exit(importConfig(flag.Args()[1:], logger))
+	}
+
+	if *pluginInfo != "" {
+		b, err := config.PluginInfo(*pluginInfo)
+		_, _ = os.Stdout.Write(b)
+
+		if err != nil {
+			os.Exit(1)
+		}
+
+		os.Exit(0)
+	}
+
+	if *checkFlag {
+		if flag.NArg() > 0 {
+			configFile = flag.Arg(0)
+		}
+
+		os.Exit(checkConfig(configFile, logger))
+	}
+
 	var initErrors []error
 
 	cfg, cfgError := loadConfig(configFile)
@@ -76,7 +104,27 @@ func main() {
 	}
 
 	if *dumpConfigFlag {
-		b, err := config.Dump(cfg)
+		var (
+			b   []byte
+			err error
+		)
+
+		if *resolvedFlag {
+			if err := config.InitPlugins(logger); err != nil {
+				logger.Errorf("Failed to init plugins: %s", err)
+			}
+
+			b, err = config.DumpResolved(cfg, func(wcfg config.WidgetConfig) (interface{}, error) {
+				_, params, err := registry.DecodeParams(wcfg)
+
+				return params, err
+			})
+
+			config.ShutdownPlugins(logger)
+		} else {
+			b, err = config.Dump(cfg)
+		}
+
 		if err != nil {
 			logger.Errorf("Failed to dump config: %s", err)
 			os.Exit(1)
@@ -86,6 +134,24 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *schemaFlag {
+		if err := config.InitPlugins(logger); err != nil {
+			logger.Errorf("Failed to init plugins: %s", err)
+		}
+
+		b, err := config.Schema()
+
+		config.ShutdownPlugins(logger)
+
+		if err != nil {
+			logger.Errorf("Failed to generate schema: %s", err)
+			os.Exit(1)
+		}
+
+		_, _ = os.Stdout.Write(append(b, '\n'))
+		os.Exit(0)
+	}
+
 	if err := config.InitPlugins(logger); err != nil {
 		logger.Errorf("Failed to init plugins: %s", err)
 		initErrors = append(initErrors, err)
@@ -139,24 +205,35 @@ func main() {
 }
 
 func loadConfig(configFile string) (*config.Config, error) {
-	if configFile == "" {
-		configDir, err := os.UserConfigDir()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get config dir: %w", err)
-		}
+	path, err := configPath(configFile)
+	if err != nil {
+		return nil, err
+	}
 
-		cfg, err := config.LoadFile(configDir + "/yagostatus/yagostatus.yml")
-		if os.IsNotExist(err) {
-			cfg, err := config.LoadFile("yagostatus.yml")
-			if os.IsNotExist(err) {
-				return config.Parse(builtinConfig, "builtin")
-			}
+	if path == "builtin" {
+		return config.Parse(builtinConfig, "builtin")
+	}
 
-			return cfg, err
-		}
+	return config.LoadFile(path)
+}
 
-		return cfg, err
+// configPath returns the config file, the default locations are checked if it is not set.
+// "builtin" is returned if there is no config file.
+func configPath(configFile string) (string, error) {
+	if configFile != "" {
+		return configFile, nil
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config dir: %w", err)
+	}
+
+	for _, path := range []string{configDir + "/yagostatus/yagostatus.yml", "yagostatus.yml"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			return path, nil
+		}
 	}
 
-	return config.LoadFile(configFile)
+	return "builtin", nil
 }
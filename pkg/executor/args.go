@@ -0,0 +1,97 @@
+package executor
+
+import (
+	"errors"
+	"strings"
+)
+
+// SplitArgs splits the command line into arguments like a POSIX shell,
+// without expansions: single quotes preserve the text as is, a backslash escapes
+// the next character outside of quotes and \, ", $, ` and the newline in double quotes.
+func SplitArgs(s string) ([]string, error) {
+	var (
+		args []string
+		arg  strings.Builder
+		// inArg is set if the argument is started, so '' is an empty argument
+		inArg bool
+	)
+
+	runes := []rune(s)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case r == '\\':
+			i++
+			if i == len(runes) {
+				return nil, errors.New("unexpected end of command after '\\'")
+			}
+
+			// a backslash-newline is a line continuation
+			if runes[i] != '\n' {
+				arg.WriteRune(runes[i])
+				inArg = true
+			}
+		case r == '\'':
+			end := -1
+
+			for j := i + 1; j < len(runes); j++ {
+				if runes[j] == '\'' {
+					end = j
+
+					break
+				}
+			}
+
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+
+			arg.WriteString(string(runes[i+1 : end]))
+			inArg = true
+			i = end
+		case r == '"':
+			closed := false
+
+			for i++; i < len(runes); i++ {
+				if runes[i] == '"' {
+					closed = true
+
+					break
+				}
+
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\\\"$`\n", runes[i+1]) {
+					i++
+
+					if runes[i] == '\n' {
+						continue
+					}
+				}
+
+				arg.WriteRune(runes[i])
+			}
+
+			if !closed {
+				return nil, errors.New("unterminated double quote")
+			}
+
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	return args, nil
+}
@@ -0,0 +1,106 @@
+package executor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{``, nil},
+		{`   `, nil},
+		{`ls`, []string{"ls"}},
+		{`ls -la  /tmp`, []string{"ls", "-la", "/tmp"}},
+		{"a\tb\nc", []string{"a", "b", "c"}},
+		{`echo 'hello world'`, []string{"echo", "hello world"}},
+		{`echo "hello world"`, []string{"echo", "hello world"}},
+		{`echo ''`, []string{"echo", ""}},
+		{`echo ""`, []string{"echo", ""}},
+		{`echo '' x`, []string{"echo", "", "x"}},
+		{`echo 'it'"'"'s'`, []string{"echo", "it's"}},
+		{`echo "it's"`, []string{"echo", "it's"}},
+		{`echo 'say "hi"'`, []string{"echo", `say "hi"`}},
+		{`echo "say \"hi\""`, []string{"echo", `say "hi"`}},
+		{`echo a"b c"d`, []string{"echo", "ab cd"}},
+		{`echo a'b c'd`, []string{"echo", "ab cd"}},
+		{`echo 'a\nb'`, []string{"echo", `a\nb`}},
+		{`echo "a\nb"`, []string{"echo", `a\nb`}},
+		{`echo "a\\b"`, []string{"echo", `a\b`}},
+		{`echo "\$HOME \` + "`" + `"`, []string{"echo", "$HOME `"}},
+		{`echo a\ b`, []string{"echo", "a b"}},
+		{`echo \'x\'`, []string{"echo", "'x'"}},
+		{`echo \"x\"`, []string{"echo", `"x"`}},
+		{`echo \\`, []string{"echo", `\`}},
+		{`echo \x`, []string{"echo", "x"}},
+		{"echo a\\\nb", []string{"echo", "ab"}},
+		{"echo \"a\\\nb\"", []string{"echo", "ab"}},
+		{`echo $HOME *`, []string{"echo", "$HOME", "*"}},
+		{`jq -r '.[] | "\(.name)"'`, []string{"jq", "-r", `.[] | "\(.name)"`}},
+		{`sh -c 'echo "a b"; exit 1'`, []string{"sh", "-c", `echo "a b"; exit 1`}},
+		{`echo "привет мир" ☺`, []string{"echo", "привет мир", "☺"}},
+	}
+
+	for _, tt := range tests {
+		got, err := SplitArgs(tt.in)
+		if err != nil {
+			t.Errorf("SplitArgs(%q) error: %s", tt.in, err)
+
+			continue
+		}
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitArgs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSplitArgsErrors(t *testing.T) {
+	tests := []string{
+		`echo 'unterminated`,
+		`echo "unterminated`,
+		`echo "escaped quote\"`,
+		`echo trailing\`,
+	}
+
+	for _, in := range tests {
+		if got, err := SplitArgs(in); err == nil {
+			t.Errorf("SplitArgs(%q) = %q, want error", in, got)
+		}
+	}
+}
+
+func TestCommandArgs(t *testing.T) {
+	tests := []struct {
+		cmd   Command
+		shell string
+		want  []string
+	}{
+		{Command{Line: `echo "a b"`}, "sh", []string{"sh", "-c", `echo "a b"`}},
+		{Command{Line: `echo "a b"`}, "bash", []string{"bash", "-c", `echo "a b"`}},
+		{Command{Line: `echo "a b"`}, ShellNone, []string{"echo", "a b"}},
+		{Command{Line: `echo "a b"`}, "", []string{"echo", "a b"}},
+		{Command{Argv: []string{"echo", "a 'b'"}}, "sh", []string{"echo", "a 'b'"}},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.cmd.Args(tt.shell)
+		if err != nil {
+			t.Errorf("%+v.Args(%q) error: %s", tt.cmd, tt.shell, err)
+
+			continue
+		}
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v.Args(%q) = %q, want %q", tt.cmd, tt.shell, got, tt.want)
+		}
+	}
+
+	for _, cmd := range []Command{{Line: "  "}, {Argv: []string{}}, {Line: `'x`}} {
+		if got, err := cmd.Args(ShellNone); err == nil {
+			t.Errorf("%+v.Args() = %q, want error", cmd, got)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package executor
+
+import (
+	"errors"
+)
+
+// ShellNone runs the command line without a shell.
+const ShellNone = "none"
+
+// Command is a command line or a list of arguments (argv) in the config.
+type Command struct {
+	Line string
+	Argv []string
+}
+
+// UnmarshalYAML unmarshals a string or a list of strings.
+func (c *Command) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var argv []string
+	if err := unmarshal(&argv); err == nil {
+		*c = Command{Argv: argv}
+
+		return nil
+	}
+
+	var line string
+	if err := unmarshal(&line); err != nil {
+		return errors.New("command should be a string or a list of strings")
+	}
+
+	*c = Command{Line: line}
+
+	return nil
+}
+
+// MarshalYAML marshals the command as it was written.
+func (c Command) MarshalYAML() (interface{}, error) {
+	if c.Argv != nil {
+		return c.Argv, nil
+	}
+
+	return c.Line, nil
+}
+
+// IsEmpty reports whether the command is not set.
+func (c Command) IsEmpty() bool {
+	return c.Line == "" && len(c.Argv) == 0
+}
+
+// Args returns the arguments to execute.
+// The command line is executed by the shell (sh, bash, zsh, fish, etc.) with -c
+// or split into arguments if the shell is empty or ShellNone, argv is executed as is.
+func (c Command) Args(shell string) ([]string, error) {
+	if c.Argv != nil {
+		if len(c.Argv) == 0 || c.Argv[0] == "" {
+			return nil, errors.New("empty command")
+		}
+
+		return c.Argv, nil
+	}
+
+	if shell != "" && shell != ShellNone {
+		return []string{shell, "-c", c.Line}, nil
+	}
+
+	args, err := SplitArgs(c.Line)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 {
+		return nil, errors.New("empty command")
+	}
+
+	return args, nil
+}
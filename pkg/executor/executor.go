@@ -9,8 +9,9 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
-	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 
 	"github.com/burik666/yagostatus/ygs"
@@ -23,25 +24,61 @@ const (
 	OutputFormatNone OutputFormat = "none"
 	OutputFormatText OutputFormat = "text"
 	OutputFormatJSON OutputFormat = "json"
+	// OutputFormatTextStream replaces the blocks on each line of output.
+	OutputFormatTextStream OutputFormat = "text-stream"
+	// OutputFormatJSONLines replaces the blocks on each line of output, a line is a block or an array of blocks.
+	OutputFormatJSONLines OutputFormat = "json-lines"
+	// OutputFormatI3Blocks is the output of i3blocks blocklets:
+	// full_text, short_text, color, background and border lines.
+	OutputFormatI3Blocks OutputFormat = "i3blocks"
 )
 
+// I3BlocksUrgentExitCode marks the block as urgent in the i3blocks format.
+const I3BlocksUrgentExitCode = 33
+
+// maxLineSize is the maximum size of a line in the line formats.
+const maxLineSize = 1024 * 1024
+
 type Executor struct {
 	cmd    *exec.Cmd
 	header *ygs.I3BarHeader
 
+	delimiter string
+	onStart   func()
+	onHeader  func(*ygs.I3BarHeader)
+
+	// m protects the process state, Shutdown and Signal are called from other goroutines
+	m        sync.Mutex
+	started  bool
+	canceled bool
 	finished bool
+	waitOnce sync.Once
 	waiterr  error
 }
 
+// Exec prepares the command, the command line is split by SplitArgs and the args are appended.
 func Exec(command string, args ...string) (*Executor, error) {
-	r := regexp.MustCompile("'.+'|\".+\"|\\S+")
-	m := r.FindAllString(command, -1)
-	name := m[0]
-	args = append(m[1:], args...)
+	m, err := SplitArgs(command)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(m) == 0 {
+		return nil, errors.New("empty command")
+	}
+
+	return ExecArgv(append(m, args...))
+}
+
+// ExecArgv prepares the command from the list of arguments.
+func ExecArgv(argv []string) (*Executor, error) {
+	if len(argv) == 0 {
+		return nil, errors.New("empty command")
+	}
 
 	e := &Executor{}
 
-	e.cmd = exec.Command(name, args...)
+	e.cmd = exec.Command(argv[0], argv[1:]...)
 	e.cmd.Env = os.Environ()
 	e.cmd.SysProcAttr = &syscall.SysProcAttr{
 		Setpgid: true,
@@ -57,6 +94,22 @@ func (e *Executor) SetWD(wd string) {
 	}
 }
 
+// OnStart sets the function called after the process is started.
+func (e *Executor) OnStart(f func()) {
+	e.onStart = f
+}
+
+// OnHeader sets the function called after the first message of the json format is read,
+// the header is nil if the first message is not a header.
+func (e *Executor) OnHeader(f func(*ygs.I3BarHeader)) {
+	e.onHeader = f
+}
+
+// SetDelimiter sets the delimiter that splits a line into blocks in the text-stream format.
+func (e *Executor) SetDelimiter(delimiter string) {
+	e.delimiter = delimiter
+}
+
 func (e *Executor) Run(logger ygs.Logger, c chan<- []ygs.I3BarBlock, format OutputFormat) error {
 	stderr, err := e.cmd.StderrPipe()
 	if err != nil {
@@ -79,10 +132,26 @@ func (e *Executor) Run(logger ygs.Logger, c chan<- []ygs.I3BarBlock, format Outp
 
 	defer stdout.Close()
 
-	if err := e.cmd.Start(); err != nil {
+	// the command is not started if Shutdown is called before
+	e.m.Lock()
+	if e.canceled {
+		e.m.Unlock()
+
+		return nil
+	}
+
+	err = e.cmd.Start()
+	e.started = err == nil
+	e.m.Unlock()
+
+	if err != nil {
 		return err
 	}
 
+	if e.onStart != nil {
+		e.onStart()
+	}
+
 	defer func() {
 		_ = e.wait()
 	}()
@@ -91,6 +160,14 @@ func (e *Executor) Run(logger ygs.Logger, c chan<- []ygs.I3BarBlock, format Outp
 		return nil
 	}
 
+	if format == OutputFormatTextStream || format == OutputFormatJSONLines {
+		return e.readLines(logger, stdout, c, format)
+	}
+
+	if format == OutputFormatI3Blocks {
+		return e.readI3Blocks(stdout, c)
+	}
+
 	buf := &bufferCloser{}
 	outreader := io.TeeReader(stdout, buf)
 
@@ -145,7 +222,13 @@ func (e *Executor) Run(logger ygs.Logger, c chan<- []ygs.I3BarBlock, format Outp
 
 	var header ygs.I3BarHeader
 	if err := headerDecoder.Decode(&header); err == nil {
+		e.m.Lock()
 		e.header = &header
+		e.m.Unlock()
+
+		if e.onHeader != nil {
+			e.onHeader(&header)
+		}
 
 		_, err := decoder.Token()
 		if err != nil {
@@ -156,6 +239,11 @@ func (e *Executor) Run(logger ygs.Logger, c chan<- []ygs.I3BarBlock, format Outp
 		if err := json.Unmarshal(firstMessageData, &blocks); err != nil {
 			return err
 		}
+
+		if e.onHeader != nil {
+			e.onHeader(nil)
+		}
+
 		c <- blocks
 	}
 
@@ -170,7 +258,7 @@ func (e *Executor) Run(logger ygs.Logger, c chan<- []ygs.I3BarBlock, format Outp
 				return nil
 			}
 
-			if e.finished {
+			if e.isFinished() {
 				return nil
 			}
 
@@ -180,6 +268,156 @@ func (e *Executor) Run(logger ygs.Logger, c chan<- []ygs.I3BarBlock, format Outp
 	}
 }
 
+// readLines sends the blocks as soon as a line is read.
+func (e *Executor) readLines(logger ygs.Logger, r io.ReadCloser, c chan<- []ygs.I3BarBlock, format OutputFormat) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLineSize)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if format == OutputFormatTextStream {
+			c <- e.textBlocks(line)
+
+			continue
+		}
+
+		blocks, err := jsonLineBlocks(line)
+		if err != nil {
+			logger.Errorf("invalid line '%s': %s", line, err)
+
+			continue
+		}
+
+		c <- blocks
+	}
+
+	if err := scanner.Err(); err != nil {
+		// the output is not read anymore, the command would block on write
+		r.Close()
+		_ = e.Shutdown()
+
+		return err
+	}
+
+	return nil
+}
+
+// readI3Blocks sends the block after the blocklet exits, an empty output hides the block.
+func (e *Executor) readI3Blocks(r io.Reader, c chan<- []ygs.I3BarBlock) error {
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	// the exit code is checked by the caller
+	_ = e.wait()
+
+	if len(bytes.TrimSpace(out)) == 0 {
+		c <- []ygs.I3BarBlock{}
+
+		return nil
+	}
+
+	block := I3BlocksBlock(string(out))
+	if state := e.cmd.ProcessState; state != nil && state.ExitCode() == I3BlocksUrgentExitCode {
+		block.Urgent = true
+	}
+
+	c <- []ygs.I3BarBlock{block}
+
+	return nil
+}
+
+// I3BlocksBlock parses the lines of the i3blocks blocklet output:
+// full_text, short_text, color, background and border, other lines are ignored.
+func I3BlocksBlock(out string) ygs.I3BarBlock {
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+
+	var block ygs.I3BarBlock
+
+	fields := []*string{&block.FullText, &block.ShortText, &block.Color, &block.BackgroundColor, &block.BorderColor}
+	for i := range fields {
+		if i < len(lines) {
+			*fields[i] = strings.TrimRight(lines[i], "\r")
+		}
+	}
+
+	return block
+}
+
+// I3BlocksEnv returns the i3blocks environment variables of the click event.
+// BLOCK_NAME and BLOCK_INSTANCE are not set if the block has no name or instance,
+// so they can be set in the widget environment.
+func I3BlocksEnv(event ygs.I3BarClickEvent) []string {
+	env := []string{
+		"BLOCK_BUTTON=" + strconv.Itoa(int(event.Button)),
+		"BLOCK_MODIFIERS=" + strings.Join(event.Modifiers, ","),
+		"BLOCK_X=" + strconv.Itoa(int(event.X)),
+		"BLOCK_Y=" + strconv.Itoa(int(event.Y)),
+		"BLOCK_RELATIVE_X=" + strconv.Itoa(int(event.RelativeX)),
+		"BLOCK_RELATIVE_Y=" + strconv.Itoa(int(event.RelativeY)),
+		"BLOCK_OUTPUT_X=" + strconv.Itoa(int(event.OutputX)),
+		"BLOCK_OUTPUT_Y=" + strconv.Itoa(int(event.OutputY)),
+		"BLOCK_WIDTH=" + strconv.Itoa(int(event.Width)),
+		"BLOCK_HEIGHT=" + strconv.Itoa(int(event.Height)),
+	}
+
+	if event.Name != "" {
+		env = append(env, "BLOCK_NAME="+event.Name)
+	}
+
+	if event.Instance != "" {
+		env = append(env, "BLOCK_INSTANCE="+event.Instance)
+	}
+
+	return env
+}
+
+// textBlocks splits the line into blocks, an empty line clears the widget.
+func (e *Executor) textBlocks(line string) []ygs.I3BarBlock {
+	if strings.TrimSpace(line) == "" {
+		return []ygs.I3BarBlock{}
+	}
+
+	parts := []string{line}
+	if e.delimiter != "" {
+		parts = strings.Split(line, e.delimiter)
+	}
+
+	blocks := make([]ygs.I3BarBlock, len(parts))
+	for i := range parts {
+		blocks[i] = ygs.I3BarBlock{
+			FullText: strings.TrimSpace(parts[i]),
+		}
+	}
+
+	return blocks
+}
+
+func jsonLineBlocks(line string) ([]ygs.I3BarBlock, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return []ygs.I3BarBlock{}, nil
+	}
+
+	if strings.HasPrefix(line, "{") {
+		var block ygs.I3BarBlock
+		if err := json.Unmarshal([]byte(line), &block); err != nil {
+			return nil, err
+		}
+
+		return []ygs.I3BarBlock{block}, nil
+	}
+
+	var blocks []ygs.I3BarBlock
+	if err := json.Unmarshal([]byte(line), &blocks); err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
 func (e *Executor) Stdin() (io.WriteCloser, error) {
 	return e.cmd.StdinPipe()
 }
@@ -188,31 +426,65 @@ func (e *Executor) AddEnv(env ...string) {
 	e.cmd.Env = append(e.cmd.Env, env...)
 }
 
+// wait waits for the started process once.
 func (e *Executor) wait() error {
-	if e.finished {
-		return e.waiterr
+	e.m.Lock()
+	started := e.started
+	e.m.Unlock()
+
+	if !started {
+		return nil
 	}
 
-	e.waiterr = e.cmd.Wait()
-	e.finished = true
+	e.waitOnce.Do(func() {
+		err := e.cmd.Wait()
+
+		e.m.Lock()
+		e.waiterr = err
+		e.finished = true
+		e.m.Unlock()
+	})
+
+	e.m.Lock()
+	defer e.m.Unlock()
 
 	return e.waiterr
 }
 
+func (e *Executor) isFinished() bool {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	return e.finished
+}
+
+// Shutdown terminates the process group, the process is not started if it is not started yet.
 func (e *Executor) Shutdown() error {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	if !e.started {
+		e.canceled = true
+
+		return nil
+	}
+
 	if e.finished {
 		return nil
 	}
 
-	if e.cmd != nil && e.cmd.Process != nil && e.cmd.Process.Pid > 1 {
+	if e.cmd.Process.Pid > 1 {
 		return syscall.Kill(-e.cmd.Process.Pid, syscall.SIGTERM)
 	}
 
-	return e.wait()
+	return nil
 }
 
 func (e *Executor) Signal(sig syscall.Signal) error {
-	if e.cmd != nil && e.cmd.Process != nil && e.cmd.Process.Pid > 1 {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	if e.started && !e.finished && e.cmd.Process.Pid > 1 {
 		return syscall.Kill(-e.cmd.Process.Pid, sig)
 	}
 
@@ -224,6 +496,9 @@ func (e *Executor) ProcessState() *os.ProcessState {
 }
 
 func (e *Executor) I3BarHeader() *ygs.I3BarHeader {
+	e.m.Lock()
+	defer e.m.Unlock()
+
 	return e.header
 }
 
@@ -0,0 +1,103 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []string{
+		``,
+		`* * * *`,
+		`* * * * * *`,
+		`60 * * * *`,
+		`* 24 * * *`,
+		`* * 0 * *`,
+		`* * * 13 *`,
+		`* * * * 8`,
+		`5-1 * * * *`,
+		`*/0 * * * *`,
+		`x * * * *`,
+		`* * * foo *`,
+		`@every`,
+	}
+
+	for _, expr := range tests {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q): want error", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	date := func(s string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return tm
+	}
+
+	tests := []struct {
+		expr string
+		from string
+		want string
+	}{
+		{`* * * * *`, "2024-01-01 10:00:30", "2024-01-01 10:01:00"},
+		{`* * * * *`, "2024-01-01 10:00:00", "2024-01-01 10:01:00"},
+		{`*/15 * * * *`, "2024-01-01 10:07:00", "2024-01-01 10:15:00"},
+		{`5/20 * * * *`, "2024-01-01 10:26:00", "2024-01-01 10:45:00"},
+		{`0,30 9-17 * * *`, "2024-01-01 17:30:00", "2024-01-02 09:00:00"},
+		{`0 0 * * *`, "2024-12-31 23:59:00", "2025-01-01 00:00:00"},
+		{`0 12 29 feb *`, "2023-03-01 00:00:00", "2024-02-29 12:00:00"},
+		{`0 0 31 * *`, "2024-04-01 00:00:00", "2024-05-31 00:00:00"},
+		{`0 0 * * 7`, "2024-01-01 00:00:00", "2024-01-07 00:00:00"},
+		{`0 0 * * sun`, "2024-01-01 00:00:00", "2024-01-07 00:00:00"},
+		{`0 0 * * mon-fri`, "2024-01-05 12:00:00", "2024-01-08 00:00:00"},
+		{`0 0 * JAN-MAR *`, "2024-04-01 00:00:00", "2025-01-01 00:00:00"},
+
+		// 2024-01-01 is a monday, dom and dow restricted: dom OR dow
+		{`0 0 13 * 5`, "2024-01-01 00:00:00", "2024-01-05 00:00:00"},
+		{`0 0 3 * 5`, "2024-01-01 00:00:00", "2024-01-03 00:00:00"},
+		// one of them starts with '*' or covers the whole range: dom AND dow
+		{`0 0 */1 * 5`, "2024-01-01 00:00:00", "2024-01-05 00:00:00"},
+		{`0 0 */2 * 5`, "2024-01-01 00:00:00", "2024-01-05 00:00:00"},
+		{`0 0 */2 * 5`, "2024-01-05 00:00:00", "2024-01-19 00:00:00"},
+		{`0 0 1-31 * 5`, "2024-01-01 00:00:00", "2024-01-05 00:00:00"},
+		{`0 0 13 * 0-6`, "2024-01-01 00:00:00", "2024-01-13 00:00:00"},
+		{`0 0 13 * */1`, "2024-01-01 00:00:00", "2024-01-13 00:00:00"},
+
+		{`@hourly`, "2024-01-01 10:00:00", "2024-01-01 11:00:00"},
+		{`@hourly`, "2024-01-01 10:59:59", "2024-01-01 11:00:00"},
+		{`@daily`, "2024-01-01 10:00:00", "2024-01-02 00:00:00"},
+		{`@midnight`, "2024-01-31 00:00:00", "2024-02-01 00:00:00"},
+		{`@weekly`, "2024-01-01 00:00:00", "2024-01-07 00:00:00"},
+		{`@monthly`, "2024-01-15 00:00:00", "2024-02-01 00:00:00"},
+		{`@yearly`, "2024-01-01 00:00:00", "2025-01-01 00:00:00"},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q) error: %s", tt.expr, err)
+
+			continue
+		}
+
+		if got := c.Next(date(tt.from)); !got.Equal(date(tt.want)) {
+			t.Errorf("ParseCron(%q).Next(%s) = %s, want %s", tt.expr, tt.from, got.Format("2006-01-02 15:04:05"), tt.want)
+		}
+	}
+}
+
+func TestCronNextNever(t *testing.T) {
+	c, err := ParseCron(`0 0 30 feb *`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.Next(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Errorf("Next() = %s, want zero time", got)
+	}
+}
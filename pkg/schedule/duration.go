@@ -0,0 +1,55 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Duration is a duration that can be written as seconds (5) or as a duration string (500ms, 1h30m).
+type Duration time.Duration
+
+// ParseDuration parses seconds or a duration string.
+func ParseDuration(s string) (Duration, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return Duration(time.Duration(n) * time.Second), nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration '%s'", s)
+	}
+
+	return Duration(d), nil
+}
+
+// UnmarshalYAML decodes seconds or a duration string.
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+
+	v, err := ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = v
+
+	return nil
+}
+
+// MarshalYAML encodes the duration as seconds if possible.
+func (d Duration) MarshalYAML() (interface{}, error) {
+	if time.Duration(d)%time.Second == 0 {
+		return int64(time.Duration(d) / time.Second), nil
+	}
+
+	return d.String(), nil
+}
+
+// String returns the duration string.
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
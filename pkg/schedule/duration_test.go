@@ -0,0 +1,81 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"0", 0},
+		{"5", 5 * time.Second},
+		{"-1", -time.Second},
+		{"500ms", 500 * time.Millisecond},
+		{"1h30m", 90 * time.Minute},
+		{"1.5s", 1500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.in)
+		if err != nil {
+			t.Errorf("ParseDuration(%q) error: %s", tt.in, err)
+
+			continue
+		}
+
+		if time.Duration(got) != tt.want {
+			t.Errorf("ParseDuration(%q) = %s, want %s", tt.in, time.Duration(got), tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "5x", "1.5", "m"} {
+		if _, err := ParseDuration(in); err == nil {
+			t.Errorf("ParseDuration(%q): want error", in)
+		}
+	}
+}
+
+func TestDurationYAML(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		out  string
+	}{
+		{"interval: 5\n", 5 * time.Second, "interval: 5\n"},
+		{"interval: 500ms\n", 500 * time.Millisecond, "interval: 500ms\n"},
+		{"interval: 2m\n", 2 * time.Minute, "interval: 120\n"},
+		{"interval: -1\n", -time.Second, "interval: -1\n"},
+	}
+
+	for _, tt := range tests {
+		var v struct {
+			Interval Duration
+		}
+
+		if err := yaml.Unmarshal([]byte(tt.in), &v); err != nil {
+			t.Errorf("Unmarshal(%q) error: %s", tt.in, err)
+
+			continue
+		}
+
+		if time.Duration(v.Interval) != tt.want {
+			t.Errorf("Unmarshal(%q) = %s, want %s", tt.in, time.Duration(v.Interval), tt.want)
+		}
+
+		out, err := yaml.Marshal(v)
+		if err != nil {
+			t.Errorf("Marshal(%q) error: %s", tt.in, err)
+
+			continue
+		}
+
+		if string(out) != tt.out {
+			t.Errorf("Marshal(%q) = %q, want %q", tt.in, out, tt.out)
+		}
+	}
+}
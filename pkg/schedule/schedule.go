@@ -0,0 +1,86 @@
+// Package schedule implements cron expressions, wall-clock aligned intervals and tickers without drift.
+package schedule
+
+import (
+	"time"
+)
+
+// Schedule returns the next activation time after t, the zero time if there is none.
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// Every is a schedule with a fixed interval.
+type Every struct {
+	Interval time.Duration
+	// Align aligns the activations to the wall clock, e.g. a 1m interval runs at :00 of each minute.
+	Align bool
+}
+
+// Next returns t plus the interval or the next interval boundary if aligned.
+func (e Every) Next(t time.Time) time.Time {
+	if !e.Align {
+		return t.Add(e.Interval)
+	}
+
+	// boundaries are counted from the local midnight
+	_, offset := t.Zone()
+	shift := time.Duration(offset) * time.Second
+
+	return t.Add(shift).Truncate(e.Interval).Add(e.Interval).Add(-shift)
+}
+
+// Ticker delivers the activation times of the schedule.
+// Each activation is computed from the previous one, so it does not drift,
+// activations missed while the receiver was busy or the system was suspended are dropped.
+// C is closed when the ticker is stopped or the schedule has no more activations.
+type Ticker struct {
+	C <-chan time.Time
+
+	stop chan struct{}
+}
+
+// NewTicker starts the ticker.
+func NewTicker(s Schedule) *Ticker {
+	c := make(chan time.Time, 1)
+	t := &Ticker{
+		C:    c,
+		stop: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(c)
+
+		next := s.Next(time.Now())
+
+		for !next.IsZero() {
+			timer := time.NewTimer(time.Until(next))
+
+			select {
+			case <-t.stop:
+				timer.Stop()
+
+				return
+			case tm := <-timer.C:
+				select {
+				case c <- tm:
+				default:
+				}
+			}
+
+			now := time.Now()
+
+			next = s.Next(next)
+			if next.Before(now) {
+				next = s.Next(now)
+			}
+		}
+	}()
+
+	return t
+}
+
+// Stop stops the ticker.
+func (t *Ticker) Stop() {
+	close(t.stop)
+}
@@ -0,0 +1,63 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEveryNext(t *testing.T) {
+	zone := time.FixedZone("UTC+3", 3*60*60)
+
+	tests := []struct {
+		every Every
+		from  time.Time
+		want  time.Time
+	}{
+		{Every{Interval: time.Minute}, time.Date(2024, 1, 1, 10, 0, 30, 0, zone), time.Date(2024, 1, 1, 10, 1, 30, 0, zone)},
+		{Every{Interval: time.Minute, Align: true}, time.Date(2024, 1, 1, 10, 0, 30, 0, zone), time.Date(2024, 1, 1, 10, 1, 0, 0, zone)},
+		{Every{Interval: time.Minute, Align: true}, time.Date(2024, 1, 1, 10, 1, 0, 0, zone), time.Date(2024, 1, 1, 10, 2, 0, 0, zone)},
+		{Every{Interval: 15 * time.Minute, Align: true}, time.Date(2024, 1, 1, 10, 7, 0, 0, zone), time.Date(2024, 1, 1, 10, 15, 0, 0, zone)},
+		// aligned to the local midnight, not to the UTC one
+		{Every{Interval: 5 * time.Hour, Align: true}, time.Date(2024, 1, 1, 7, 0, 0, 0, zone), time.Date(2024, 1, 1, 10, 0, 0, 0, zone)},
+		{Every{Interval: 24 * time.Hour, Align: true}, time.Date(2024, 1, 1, 7, 0, 0, 0, zone), time.Date(2024, 1, 2, 0, 0, 0, 0, zone)},
+	}
+
+	for _, tt := range tests {
+		if got := tt.every.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%+v.Next(%s) = %s, want %s", tt.every, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestTickerAligned(t *testing.T) {
+	interval := 50 * time.Millisecond
+
+	ticker := NewTicker(Every{Interval: interval, Align: true})
+
+	for i := 0; i < 3; i++ {
+		select {
+		case tm := <-ticker.C:
+			// the interval divides the zone offset, so the local and UTC boundaries are the same
+			if rem := tm.Sub(tm.Truncate(interval)); rem > 20*time.Millisecond {
+				t.Errorf("tick %s is %s after the boundary", tm, rem)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("no tick")
+		}
+	}
+
+	ticker.Stop()
+
+	timeout := time.After(time.Second)
+
+	for {
+		select {
+		case _, ok := <-ticker.C:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("C is not closed after Stop")
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Package watch notifies about changes of files matching the patterns.
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// pattern is an absolute path or glob, dir is set if the pattern is a directory.
+type pattern struct {
+	path string
+	dir  bool
+}
+
+// resolve makes the patterns absolute, ~/ is replaced by the home directory.
+func resolve(patterns []string, workdir string) ([]pattern, error) {
+	res := make([]pattern, 0, len(patterns))
+
+	for _, p := range patterns {
+		if p == "~" || strings.HasPrefix(p, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, err
+			}
+
+			p = filepath.Join(home, strings.TrimPrefix(p, "~"))
+		}
+
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(workdir, p)
+		}
+
+		p = filepath.Clean(p)
+
+		if _, err := filepath.Match(p, ""); err != nil {
+			return nil, err
+		}
+
+		fi, err := os.Stat(p)
+		res = append(res, pattern{
+			path: p,
+			dir:  err == nil && fi.IsDir(),
+		})
+	}
+
+	return res, nil
+}
+
+// match reports whether the changed file matches the pattern.
+func (p pattern) match(dir, name string) bool {
+	if p.dir {
+		return true
+	}
+
+	ok, _ := filepath.Match(p.path, filepath.Join(dir, name))
+
+	return ok
+}
@@ -0,0 +1,276 @@
+package watch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const mask = syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY | syscall.IN_ATTRIB |
+	syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// inotify does not report changes of sysfs and procfs attributes, the files are polled.
+const (
+	procSuperMagic = 0x9fa0
+	sysfsMagic     = 0x62656572
+
+	pollInterval = 500 * time.Millisecond
+	// maxPollSize limits the size of polled files.
+	maxPollSize = 4096
+)
+
+// isPseudoFS reports whether the directory is on sysfs or procfs.
+var isPseudoFS = func(dir string) bool {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return false
+	}
+
+	return int64(st.Type) == procSuperMagic || int64(st.Type) == sysfsMagic
+}
+
+// Watcher watches the files using inotify.
+type Watcher struct {
+	// C receives a value when the watched files are changed.
+	C <-chan struct{}
+
+	c        chan struct{}
+	f        *os.File
+	debounce time.Duration
+
+	// watches maps inotify watch descriptors to the watched directories and their patterns.
+	watches map[int32]watch
+	// polled are the patterns of files on sysfs and procfs.
+	polled []pattern
+
+	done chan struct{}
+	once sync.Once
+}
+
+type watch struct {
+	dir      string
+	patterns []pattern
+}
+
+// New starts watching the files matching the patterns (paths or globs, relative to the workdir).
+// Parent directories are watched, so files can be created and replaced later,
+// the directories must exist. Changes are reported after the debounce delay without changes.
+// Files on sysfs and procfs are read every pollInterval instead, directories on them are not supported.
+func New(patterns []string, workdir string, debounce time.Duration) (*Watcher, error) {
+	resolved, err := resolve(patterns, workdir)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify: %w", err)
+	}
+
+	c := make(chan struct{}, 1)
+	w := &Watcher{
+		C:        c,
+		c:        c,
+		f:        os.NewFile(uintptr(fd), "inotify"),
+		debounce: debounce,
+		watches:  make(map[int32]watch),
+		done:     make(chan struct{}),
+	}
+
+	for _, p := range resolved {
+		dirs := []string{p.path}
+		if !p.dir {
+			if dirs, err = filepath.Glob(filepath.Dir(p.path)); err != nil {
+				w.f.Close()
+
+				return nil, err
+			}
+		}
+
+		if len(dirs) == 0 {
+			w.f.Close()
+
+			return nil, fmt.Errorf("no directory to watch for '%s'", p.path)
+		}
+
+		polled := false
+
+		for _, dir := range dirs {
+			if isPseudoFS(dir) {
+				if p.dir {
+					w.f.Close()
+
+					return nil, fmt.Errorf("watch '%s': directories on sysfs and procfs are not supported", dir)
+				}
+
+				if !polled {
+					polled = true
+					w.polled = append(w.polled, p)
+				}
+
+				continue
+			}
+
+			wd, err := syscall.InotifyAddWatch(fd, dir, mask)
+			if err != nil {
+				w.f.Close()
+
+				return nil, fmt.Errorf("watch '%s': %w", dir, err)
+			}
+
+			wt := w.watches[int32(wd)]
+			wt.dir = dir
+			wt.patterns = append(wt.patterns, p)
+			w.watches[int32(wd)] = wt
+		}
+	}
+
+	go w.run()
+
+	if len(w.polled) > 0 {
+		go w.poll()
+	}
+
+	return w, nil
+}
+
+// poll reads the polled files and reports the changes of their contents.
+func (w *Watcher) poll() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	last := w.readPolled()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		files := w.readPolled()
+
+		changed := len(files) != len(last)
+		for f, data := range files {
+			if prev, ok := last[f]; !ok || prev != data {
+				changed = true
+			}
+		}
+
+		last = files
+
+		if changed {
+			select {
+			case w.c <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// readPolled returns the contents of the files matching the polled patterns.
+func (w *Watcher) readPolled() map[string]string {
+	files := make(map[string]string)
+
+	for _, p := range w.polled {
+		matches, _ := filepath.Glob(p.path)
+
+		for _, name := range matches {
+			f, err := os.Open(name)
+			if err != nil {
+				continue
+			}
+
+			data, err := io.ReadAll(io.LimitReader(f, maxPollSize))
+			f.Close()
+
+			if err == nil {
+				files[name] = string(data)
+			}
+		}
+	}
+
+	return files
+}
+
+func (w *Watcher) run() {
+	changed := make(chan struct{}, 1)
+
+	go func() {
+		var timer *time.Timer
+
+		for range changed {
+			if timer != nil {
+				timer.Stop()
+			}
+
+			timer = time.AfterFunc(w.debounce, func() {
+				select {
+				case w.c <- struct{}{}:
+				default:
+				}
+			})
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	defer close(changed)
+
+	buf := make([]byte, syscall.SizeofInotifyEvent*64+syscall.NAME_MAX+1)
+
+	for {
+		n, err := w.f.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameb := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+
+			name := string(nameb)
+			if i := bytes.IndexByte(nameb, 0); i >= 0 {
+				name = string(nameb[:i])
+			}
+
+			wt, ok := w.watches[event.Wd]
+			if !ok {
+				continue
+			}
+
+			for _, p := range wt.patterns {
+				if p.match(wt.dir, name) {
+					select {
+					case changed <- struct{}{}:
+					default:
+					}
+
+					break
+				}
+			}
+		}
+	}
+}
+
+// Close stops watching.
+func (w *Watcher) Close() error {
+	err := errors.New("watcher already closed")
+
+	w.once.Do(func() {
+		err = w.f.Close()
+		close(w.done)
+	})
+
+	return err
+}
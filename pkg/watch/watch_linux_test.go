@@ -0,0 +1,113 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const debounce = 100 * time.Millisecond
+
+// changes counts the notifications received within the duration.
+func changes(w *Watcher, d time.Duration) int {
+	n := 0
+	timeout := time.After(d)
+
+	for {
+		select {
+		case <-w.C:
+			n++
+		case <-timeout:
+			return n
+		}
+	}
+}
+
+func writeFile(t *testing.T, name, data string) {
+	t.Helper()
+
+	if err := os.WriteFile(name, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcherDebounce(t *testing.T) {
+	dir := t.TempDir()
+
+	w, err := New([]string{"*.txt"}, dir, debounce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// changes within the debounce delay are reported once
+	for i := 0; i < 5; i++ {
+		writeFile(t, filepath.Join(dir, "a.txt"), strings.Repeat("x", i))
+		time.Sleep(debounce / 5)
+	}
+
+	if n := changes(w, 3*debounce); n != 1 {
+		t.Errorf("got %d notifications, want 1", n)
+	}
+
+	writeFile(t, filepath.Join(dir, "a.log"), "x")
+
+	if n := changes(w, 3*debounce); n != 0 {
+		t.Errorf("not matching file: got %d notifications, want 0", n)
+	}
+
+	writeFile(t, filepath.Join(dir, "b.txt"), "x")
+
+	if n := changes(w, 3*debounce); n != 1 {
+		t.Errorf("created file: got %d notifications, want 1", n)
+	}
+}
+
+func TestWatcherErrors(t *testing.T) {
+	if _, err := New([]string{"missing/a.txt"}, t.TempDir(), debounce); err == nil {
+		t.Error("missing directory: expected an error")
+	}
+
+	if _, err := New([]string{"/proc/self"}, "/", debounce); err == nil {
+		t.Error("procfs directory: expected an error")
+	}
+}
+
+func TestWatcherPoll(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "brightness")
+
+	writeFile(t, name, "10\n")
+
+	isPseudo := isPseudoFS
+	isPseudoFS = func(d string) bool {
+		return d == dir
+	}
+
+	defer func() {
+		isPseudoFS = isPseudo
+	}()
+
+	w, err := New([]string{name}, "/", debounce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if len(w.polled) != 1 || len(w.watches) != 0 {
+		t.Fatalf("got polled %v and watches %v, want the file polled", w.polled, w.watches)
+	}
+
+	if n := changes(w, 2*pollInterval); n != 0 {
+		t.Errorf("not changed: got %d notifications, want 0", n)
+	}
+
+	// the content is compared, the size is the same
+	writeFile(t, name, "20\n")
+
+	if n := changes(w, 2*pollInterval+debounce); n != 1 {
+		t.Errorf("changed: got %d notifications, want 1", n)
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+
+package watch
+
+import (
+	"errors"
+	"time"
+)
+
+// Watcher watches the files.
+type Watcher struct {
+	// C receives a value when the watched files are changed.
+	C <-chan struct{}
+}
+
+// New is not supported on this platform.
+func New(patterns []string, workdir string, debounce time.Duration) (*Watcher, error) {
+	return nil, errors.New("watching files is supported only on linux")
+}
+
+// Close stops watching.
+func (w *Watcher) Close() error {
+	return nil
+}
@@ -0,0 +1,62 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patterns, err := resolve([]string{"a.txt", "../b/*.log", "~/c", dir, "/x/./y"}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []pattern{
+		{path: filepath.Join(dir, "a.txt")},
+		{path: filepath.Join(filepath.Dir(dir), "b/*.log")},
+		{path: filepath.Join(home, "c")},
+		{path: dir, dir: true},
+		{path: "/x/y"},
+	}
+
+	for i := range want {
+		if patterns[i] != want[i] {
+			t.Errorf("#%d: got %+v, want %+v", i, patterns[i], want[i])
+		}
+	}
+
+	if _, err := resolve([]string{"[a"}, dir); err == nil {
+		t.Error("invalid glob: expected an error")
+	}
+}
+
+func TestPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern pattern
+		dir     string
+		name    string
+		match   bool
+	}{
+		{pattern{path: "/a/b.txt"}, "/a", "b.txt", true},
+		{pattern{path: "/a/b.txt"}, "/a", "c.txt", false},
+		{pattern{path: "/a/*.log"}, "/a", "x.log", true},
+		{pattern{path: "/a/*.log"}, "/a", "x.txt", false},
+		{pattern{path: "/a/*/b"}, "/a/x", "b", true},
+		{pattern{path: "/a/*/b"}, "/a/x", "c", false},
+		{pattern{path: "/a", dir: true}, "/a", "anything", true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.pattern.match(tt.dir, tt.name); got != tt.match {
+			t.Errorf("%+v %s/%s: got %v, want %v", tt.pattern, tt.dir, tt.name, got, tt.match)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package filter
+
+import (
+	"github.com/burik666/yagostatus/ygs"
+)
+
+// Params are filter parameters.
+type Params struct {
+	Prefix string `description:"Text to prepend to the blocks."`
+}
+
+// Filter implements an output filter.
+type Filter struct {
+	ygs.BlankFilter
+
+	params Params
+}
+
+// NewFilter returns a new Filter.
+func NewFilter(params interface{}, flogger ygs.Logger) (ygs.Filter, error) {
+	f := &Filter{
+		params: params.(Params),
+	}
+
+	return f, nil
+}
+
+// Widget prepends the prefix to the widget blocks.
+func (f *Filter) Widget(widget ygs.WidgetInfo, blocks []ygs.I3BarBlock) []ygs.I3BarBlock {
+	for i := range blocks {
+		blocks[i].FullText = f.params.Prefix + blocks[i].FullText
+	}
+
+	return blocks
+}
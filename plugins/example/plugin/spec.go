@@ -1,17 +1,19 @@
 package plugin
 
 import (
+	"github.com/burik666/yagostatus/plugins/example/filter"
 	"github.com/burik666/yagostatus/plugins/example/widget"
 	"github.com/burik666/yagostatus/ygs"
 )
 
 // Params contains example plugin parameters.
 type Params struct {
-	DefaultMessage string `yaml:"default_message"`
+	DefaultMessage string `yaml:"default_message" description:"Default message for the example widget."`
 }
 
 var Spec = ygs.PluginSpec{
-	Name: "example",
+	Name:       "example",
+	APIVersion: ygs.APIVersion,
 	DefaultParams: Params{
 		"not set",
 	},
@@ -29,6 +31,16 @@ var Spec = ygs.PluginSpec{
 			panic(err)
 		}
 
+		if err := ygs.RegisterFilter(ygs.FilterSpec{
+			Name:    "example",
+			NewFunc: filter.NewFilter,
+			DefaultParams: filter.Params{
+				Prefix: "> ",
+			},
+		}); err != nil {
+			panic(err)
+		}
+
 		return nil
 	},
 
@@ -6,7 +6,7 @@ import (
 
 // Params are widget parameters.
 type Params struct {
-	Message string
+	Message string `description:"Message to display."`
 }
 
 // Widget implements a widget.
@@ -0,0 +1,79 @@
+#!/usr/bin/env python3
+"""Example executable plugin for yagostatus (see ../PROTOCOL.md)."""
+
+import json
+import sys
+import threading
+
+lock = threading.Lock()
+widgets = {}
+default_message = "not set"
+
+
+def send(msg):
+    msg["jsonrpc"] = "2.0"
+    with lock:
+        sys.stdout.write(json.dumps(msg) + "\n")
+        sys.stdout.flush()
+
+
+def output(wid, blocks):
+    send({"method": "Output", "params": {"id": wid, "blocks": blocks}})
+
+
+def handle(method, params):
+    global default_message
+
+    if method == "Initialize":
+        default_message = params["params"].get("default_message", default_message)
+        return {
+            "widgets": [
+                {
+                    "name": "exec-example",
+                    "params": [
+                        {
+                            "name": "message",
+                            "type": "string",
+                            "default": default_message,
+                            "description": "Message to display.",
+                        },
+                    ],
+                },
+            ],
+        }
+
+    if method == "NewWidget":
+        widgets[params["id"]] = {"message": params["params"]["message"], "clicks": 0}
+        return None
+
+    if method == "Run":
+        w = widgets[params["id"]]
+        output(params["id"], [{"full_text": w["message"]}])
+        return None
+
+    if method == "Event":
+        w = widgets[params["id"]]
+        w["clicks"] += 1
+        output(params["id"], [{"full_text": "%s (%d)" % (w["message"], w["clicks"])}])
+        return None
+
+    if method in ("Stop", "Continue", "Shutdown"):
+        return None
+
+    raise KeyError(method)
+
+
+for line in sys.stdin:
+    msg = json.loads(line)
+    method = msg.get("method")
+
+    if method == "Exit":
+        break
+
+    if "id" not in msg:
+        continue
+
+    try:
+        send({"id": msg["id"], "result": handle(method, msg.get("params"))})
+    except KeyError:
+        send({"id": msg["id"], "error": {"code": -32601, "message": "method not found"}})
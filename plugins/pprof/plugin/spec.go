@@ -9,13 +9,14 @@ import (
 )
 
 type Params struct {
-	Listen string
+	Listen string `description:"Address to listen."`
 }
 
 var srv *http.Server
 
 var Spec = ygs.PluginSpec{
-	Name: "pprof",
+	Name:       "pprof",
+	APIVersion: ygs.APIVersion,
 	DefaultParams: Params{
 		Listen: "localhost:6060",
 	},
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"github.com/burik666/yagostatus/internal/config"
+	"github.com/burik666/yagostatus/ygs"
+)
+
+// separatorName is the name of generated separator blocks, click events on them are ignored.
+const separatorName = "yagostatus-separator"
+
+// joinOutputs concatenates the outputs of visible widgets
+// and inserts the configured separators between them.
+func (status *YaGoStatus) joinOutputs(outputs [][]ygs.I3BarBlock) []ygs.I3BarBlock {
+	var result []ygs.I3BarBlock
+
+	cfg := status.cfg.Output.Separators
+
+	for i, blocks := range outputs {
+		if i > 0 && cfg.Mode == config.SeparatorsModePowerline {
+			prev := &result[len(result)-1]
+			disableSeparator(prev)
+
+			result = append(result, powerlineSeparator(cfg, prev.BackgroundColor, blocks[0].BackgroundColor))
+		}
+
+		result = append(result, blocks...)
+	}
+
+	return result
+}
+
+func powerlineSeparator(cfg config.SeparatorsConfig, prevBackground, nextBackground string) ygs.I3BarBlock {
+	color := nextBackground
+	if color == "" {
+		color = cfg.Background
+	}
+
+	block := ygs.I3BarBlock{
+		FullText:        cfg.Glyph,
+		Color:           color,
+		BackgroundColor: prevBackground,
+		Markup:          cfg.Markup,
+		Name:            separatorName,
+	}
+
+	disableSeparator(&block)
+
+	return block
+}
+
+// disableSeparator removes the separator and the gap after the block.
+// The zero separator_block_width is omitted by the block encoder,
+// so it is set as an extra field of a copy of the custom fields.
+func disableSeparator(block *ygs.I3BarBlock) {
+	separator := false
+
+	custom := make(map[string]ygs.Vary, len(block.Custom)+1)
+	for k, v := range block.Custom {
+		custom[k] = v
+	}
+
+	custom["separator_block_width"] = ygs.Vary("0")
+
+	block.Separator = &separator
+	block.SeparatorBlockWidth = 0
+	block.Custom = custom
+}
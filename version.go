@@ -1,4 +1,6 @@
 package main
 
+import "github.com/burik666/yagostatus/ygs"
+
 // Version contains YaGoStatus version.
-const Version = "1.1.0"
+const Version = ygs.Version
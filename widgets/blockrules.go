@@ -0,0 +1,148 @@
+package widgets
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/burik666/yagostatus/ygs"
+)
+
+// BlockMatch matches blocks by name and instance, empty fields match any value.
+type BlockMatch struct {
+	Name     string `yaml:"name,omitempty" description:"Block name."`
+	Instance string `yaml:"instance,omitempty" description:"Block instance."`
+}
+
+// BlockReplace replaces the text of the matching blocks.
+type BlockReplace struct {
+	BlockMatch `yaml:",inline"`
+	Field      string `yaml:"field,omitempty" description:"full_text or short_text (default: both)."`
+	Regex      string `yaml:"regex" description:"Regular expression (https://golang.org/s/re2syntax)."`
+	With       string `yaml:"with" description:"Replacement, $1 is the first submatch."`
+}
+
+// BlockRules are parameters to filter, order and rewrite the command output blocks.
+type BlockRules struct {
+	Select  []BlockMatch   `yaml:"select,omitempty" description:"Show only the blocks matching any of the rules."`
+	Exclude []BlockMatch   `yaml:"exclude,omitempty" description:"Hide the blocks matching any of the rules."`
+	Order   []BlockMatch   `yaml:"order,omitempty" description:"Show the matching blocks first in this order, other blocks keep their order."`
+	Replace []BlockReplace `yaml:"replace,omitempty" description:"Replace the text of the matching blocks by regular expressions."`
+}
+
+type blockReplacer struct {
+	BlockReplace
+	re *regexp.Regexp
+}
+
+// blockRules applies BlockRules, the block names and instances are kept,
+// so click events are routed to the original blocks.
+type blockRules struct {
+	params   BlockRules
+	replaces []blockReplacer
+}
+
+func newBlockRules(params BlockRules) (*blockRules, error) {
+	if len(params.Select) == 0 && len(params.Exclude) == 0 && len(params.Order) == 0 && len(params.Replace) == 0 {
+		return nil, nil
+	}
+
+	for key, matches := range map[string][]BlockMatch{"select": params.Select, "exclude": params.Exclude, "order": params.Order} {
+		for i, m := range matches {
+			if m.Name == "" && m.Instance == "" {
+				return nil, fmt.Errorf("%s#%d: missing 'name' or 'instance'", key, i+1)
+			}
+		}
+	}
+
+	r := &blockRules{params: params}
+
+	for i, rp := range params.Replace {
+		switch rp.Field {
+		case "", "full_text", "short_text":
+		default:
+			return nil, fmt.Errorf("replace#%d: unknown field '%s' (expected full_text or short_text)", i+1, rp.Field)
+		}
+
+		if rp.Regex == "" {
+			return nil, fmt.Errorf("replace#%d: missing 'regex'", i+1)
+		}
+
+		re, err := regexp.Compile(rp.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("replace#%d: %w", i+1, err)
+		}
+
+		r.replaces = append(r.replaces, blockReplacer{BlockReplace: rp, re: re})
+	}
+
+	return r, nil
+}
+
+func (m BlockMatch) match(block ygs.I3BarBlock) bool {
+	return (m.Name == "" || m.Name == block.Name) && (m.Instance == "" || m.Instance == block.Instance)
+}
+
+func matchAny(matches []BlockMatch, block ygs.I3BarBlock) bool {
+	for _, m := range matches {
+		if m.match(block) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// apply returns the selected, ordered and rewritten blocks.
+func (r *blockRules) apply(blocks []ygs.I3BarBlock) []ygs.I3BarBlock {
+	if r == nil {
+		return blocks
+	}
+
+	res := make([]ygs.I3BarBlock, 0, len(blocks))
+
+	for _, block := range blocks {
+		if len(r.params.Select) > 0 && !matchAny(r.params.Select, block) {
+			continue
+		}
+
+		if matchAny(r.params.Exclude, block) {
+			continue
+		}
+
+		for _, rp := range r.replaces {
+			if !rp.match(block) {
+				continue
+			}
+
+			if rp.Field != "short_text" {
+				block.FullText = rp.re.ReplaceAllString(block.FullText, rp.With)
+			}
+
+			if rp.Field != "full_text" && block.ShortText != "" {
+				block.ShortText = rp.re.ReplaceAllString(block.ShortText, rp.With)
+			}
+		}
+
+		res = append(res, block)
+	}
+
+	if len(r.params.Order) > 0 {
+		sort.SliceStable(res, func(i, j int) bool {
+			return r.orderIndex(res[i]) < r.orderIndex(res[j])
+		})
+	}
+
+	return res
+}
+
+// orderIndex returns the index of the first matching order rule.
+func (r *blockRules) orderIndex(block ygs.I3BarBlock) int {
+	for i, m := range r.params.Order {
+		if m.match(block) {
+			return i
+		}
+	}
+
+	return len(r.params.Order)
+}
@@ -1,15 +1,17 @@
 package widgets
 
 import (
+	"errors"
 	"time"
 
+	"github.com/burik666/yagostatus/pkg/schedule"
 	"github.com/burik666/yagostatus/ygs"
 )
 
 // ClockWidgetParams are widget parameters.
 type ClockWidgetParams struct {
-	Interval uint
-	Format   string
+	Interval schedule.Duration `description:"Clock update interval in seconds or as a duration string (ticks are aligned to the wall clock)."`
+	Format   string            `description:"Time format (https://golang.org/pkg/time/#Time.Format)."`
 }
 
 // ClockWidget implements a clock.
@@ -24,7 +26,7 @@ func init() {
 		Name:    "clock",
 		NewFunc: NewClockWidget,
 		DefaultParams: ClockWidgetParams{
-			Interval: 1,
+			Interval: schedule.Duration(time.Second),
 			Format:   "Jan _2 Mon 15:04:05",
 		},
 	}); err != nil {
@@ -38,6 +40,10 @@ func NewClockWidget(params interface{}, wlogger ygs.Logger) (ygs.Widget, error)
 		params: params.(ClockWidgetParams),
 	}
 
+	if w.params.Interval <= 0 {
+		return nil, errors.New("interval should be positive")
+	}
+
 	return w, nil
 }
 
@@ -50,7 +56,10 @@ func (w *ClockWidget) Run(c chan<- []ygs.I3BarBlock) error {
 
 	c <- res
 
-	ticker := time.NewTicker(time.Duration(w.params.Interval) * time.Second)
+	ticker := schedule.NewTicker(schedule.Every{
+		Interval: time.Duration(w.params.Interval),
+		Align:    true,
+	})
 	for t := range ticker.C {
 		res[0].FullText = t.Format(w.params.Format)
 		c <- res
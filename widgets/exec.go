@@ -5,28 +5,51 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/burik666/yagostatus/pkg/executor"
+	"github.com/burik666/yagostatus/pkg/schedule"
 	"github.com/burik666/yagostatus/pkg/signals"
+	"github.com/burik666/yagostatus/pkg/watch"
 	"github.com/burik666/yagostatus/ygs"
 )
 
 // ExecWidgetParams are widget parameters.
 type ExecWidgetParams struct {
-	Command      string
-	Interval     int
-	Retry        *int
-	Silent       bool
-	EventsUpdate bool `yaml:"events_update"`
-	Signal       *int
-	OutputFormat executor.OutputFormat `yaml:"output_format"`
-	WorkDir      string
-	Env          []string
+	Command        executor.Command      `description:"Command to execute: a command line (via the shell) or a list of arguments (without a shell)."`
+	Shell          string                `description:"Shell to execute the command line: sh, bash, zsh, fish, etc. or none to split the arguments without a shell."`
+	Interval       schedule.Duration     `description:"Update interval in seconds or as a duration string like 500ms or 1h30m (0 to run once, -1 to restart immediately after exit)."`
+	Align          bool                  `description:"Align the interval to the wall clock (e.g. 60 runs at :00 of each minute)."`
+	Schedule       string                `description:"Cron expression to update widget (e.g. '*/5 * * * *' or '@hourly')."`
+	Retry          *schedule.Duration    `description:"Retry interval in seconds or as a duration string if command failed."`
+	Silent         bool                  `description:"Don't show error widget if command failed."`
+	OnError        string                `yaml:"on_error" description:"Policy if command failed: error (show the error), keep (keep the last output marked as stale) or hide."`
+	MaxFailures    int                   `yaml:"max_failures" description:"Show the error after N consecutive failures with on_error: keep (0 for no limit)."`
+	MaxStale       schedule.Duration     `yaml:"max_stale" description:"Show the error if the output is older than this with on_error: keep (0 for no limit)."`
+	StaleTemplates ygs.I3BarBlocks       `yaml:"stale_templates" description:"Templates applied over stale blocks (one for all blocks or one per block)."`
+	EventsUpdate   bool                  `yaml:"events_update" description:"Update widget if an event occurred."`
+	Signal         *int                  `description:"SIGRTMIN offset to update widget."`
+	Watch          []string              `description:"Files, globs and directories to watch, the widget is updated when they are changed."`
+	WatchDebounce  schedule.Duration     `yaml:"watch_debounce" description:"Delay after the last change of the watched files before the update."`
+	OutputFormat   executor.OutputFormat `yaml:"output_format" description:"The command output format: none, text, json, auto, text-stream, json-lines or i3blocks."`
+	Delimiter      string                `description:"Split text-stream lines into blocks by the delimiter."`
+	Label          string                `description:"Prefix of the full_text of the first block."`
+	Overlap        string                `description:"Policy if the widget is updated while the command is running: skip, queue-one or kill-previous."`
+	WorkDir        string                `description:"Working directory."`
+	Env            []string              `description:"Environment variables."`
+	BlockRules     `yaml:",inline"`
 }
 
+// minRestartRun is the minimal time between the restarts with interval -1.
+const minRestartRun = time.Second
+
+// StaleSinceField is the custom field of stale blocks with the time of the last successful run (unix seconds),
+// the age of the output is computed from it when the blocks are used ($I3_STALE_SECONDS).
+const StaleSinceField = "_stale_since"
+
 // ExecWidget implements the exec widget.
 type ExecWidget struct {
 	ygs.BlankWidget
@@ -35,22 +58,50 @@ type ExecWidget struct {
 
 	logger ygs.Logger
 
-	signal  os.Signal
-	c       chan<- []ygs.I3BarBlock
-	upd     chan struct{}
-	tickerC *chan struct{}
-	env     []string
+	args     []string
+	rules    *blockRules
+	signal   os.Signal
+	schedule schedule.Schedule
+	c        chan<- []ygs.I3BarBlock
+	upd      chan struct{}
+	ticker   *schedule.Ticker
+	watcher  *watch.Watcher
+	env      []string
 
 	outputWG sync.WaitGroup
+	m        sync.Mutex
 	exc      *executor.Executor
 	shutdown bool
+	running  bool
+	// killed is set if the running command is killed by the kill-previous policy
+	killed bool
+	// clickEnv is the i3blocks environment of the click event for the next run
+	clickEnv []string
+
+	// last is the output of the current run, good is the output of the last successful run
+	last     []ygs.I3BarBlock
+	good     []ygs.I3BarBlock
+	goodTime time.Time
+	failures int
+	// isStale is set while the stale output is shown
+	isStale bool
+	// restartDelay is the backoff of the restarts with interval -1 after failures
+	restartDelay time.Duration
 }
 
 func init() {
 	if err := ygs.RegisterWidget(ygs.WidgetSpec{
-		Name:          "exec",
-		NewFunc:       NewExecWidget,
-		DefaultParams: ExecWidgetParams{},
+		Name:    "exec",
+		NewFunc: NewExecWidget,
+		DefaultParams: ExecWidgetParams{
+			Shell:         "sh",
+			OnError:       "error",
+			Overlap:       "queue-one",
+			WatchDebounce: schedule.Duration(100 * time.Millisecond),
+			StaleTemplates: ygs.I3BarBlocks{
+				{Color: "#888888"},
+			},
+		},
 	}); err != nil {
 		panic(err)
 	}
@@ -63,10 +114,21 @@ func NewExecWidget(params interface{}, wlogger ygs.Logger) (ygs.Widget, error) {
 		logger: wlogger,
 	}
 
-	if len(w.params.Command) == 0 {
+	if w.params.Command.IsEmpty() {
 		return nil, errors.New("missing 'command'")
 	}
 
+	args, err := w.params.Command.Args(w.params.Shell)
+	if err != nil {
+		return nil, fmt.Errorf("command: %w", err)
+	}
+
+	w.args = args
+
+	if w.rules, err = newBlockRules(w.params.BlockRules); err != nil {
+		return nil, err
+	}
+
 	if w.params.Retry != nil &&
 		*w.params.Retry > 0 &&
 		w.params.Interval > 0 &&
@@ -74,6 +136,47 @@ func NewExecWidget(params interface{}, wlogger ygs.Logger) (ygs.Widget, error) {
 		return nil, errors.New("restart value should be less than interval")
 	}
 
+	switch w.params.OnError {
+	case "error", "keep", "hide":
+	default:
+		return nil, fmt.Errorf("unknown on_error policy '%s' (expected error, keep or hide)", w.params.OnError)
+	}
+
+	switch w.params.Overlap {
+	case "skip", "queue-one", "kill-previous":
+	default:
+		return nil, fmt.Errorf("unknown overlap policy '%s' (expected skip, queue-one or kill-previous)", w.params.Overlap)
+	}
+
+	if w.params.MaxFailures < 0 {
+		return nil, errors.New("max_failures should be positive")
+	}
+
+	if w.params.Align && w.params.Interval <= 0 {
+		return nil, errors.New("'align' requires a positive 'interval'")
+	}
+
+	switch {
+	case w.params.Schedule != "" && w.params.Interval != 0:
+		return nil, errors.New("'schedule' and 'interval' are mutually exclusive")
+	case w.params.Schedule != "":
+		cron, err := schedule.ParseCron(w.params.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("schedule: %w", err)
+		}
+
+		w.schedule = cron
+	case w.params.Interval > 0:
+		w.schedule = schedule.Every{
+			Interval: time.Duration(w.params.Interval),
+			Align:    w.params.Align,
+		}
+	}
+
+	if w.params.Delimiter != "" && w.params.OutputFormat != executor.OutputFormatTextStream {
+		return nil, errors.New("'delimiter' requires 'output_format: text-stream'")
+	}
+
 	if w.params.Signal != nil {
 		sig := *w.params.Signal
 		if sig < 0 || signals.SIGRTMIN+sig > signals.SIGRTMAX {
@@ -83,6 +186,15 @@ func NewExecWidget(params interface{}, wlogger ygs.Logger) (ygs.Widget, error) {
 		w.signal = syscall.Signal(signals.SIGRTMIN + sig)
 	}
 
+	if len(w.params.Watch) > 0 {
+		watcher, err := watch.New(w.params.Watch, w.params.WorkDir, time.Duration(w.params.WatchDebounce))
+		if err != nil {
+			return nil, fmt.Errorf("watch: %w", err)
+		}
+
+		w.watcher = watcher
+	}
+
 	w.upd = make(chan struct{}, 1)
 	w.upd <- struct{}{}
 
@@ -90,18 +202,47 @@ func NewExecWidget(params interface{}, wlogger ygs.Logger) (ygs.Widget, error) {
 }
 
 func (w *ExecWidget) exec() error {
-	exc, err := executor.Exec("sh", "-c", w.params.Command)
+	exc, err := executor.ExecArgv(w.args)
 	if err != nil {
 		return err
 	}
 
-	w.exc = exc
-
 	exc.SetWD(w.params.WorkDir)
+	exc.SetDelimiter(w.params.Delimiter)
 
 	exc.AddEnv(w.env...)
 	exc.AddEnv(w.params.Env...)
 
+	if w.isStale {
+		exc.AddEnv(fmt.Sprintf("I3_STALE_SECONDS=%d", int(time.Since(w.goodTime).Seconds())))
+	}
+
+	w.m.Lock()
+	exc.AddEnv(w.clickEnv...)
+	w.clickEnv = nil
+	w.m.Unlock()
+
+	w.last = nil
+
+	// Shutdown of the widget cancels the command before it is started,
+	// the updates are merged into the next run until it is started
+	w.m.Lock()
+	w.exc = exc
+	w.m.Unlock()
+
+	exc.OnStart(func() {
+		w.m.Lock()
+		w.running = true
+		w.killed = false
+		w.m.Unlock()
+	})
+
+	defer (func() {
+		w.m.Lock()
+		w.running = false
+		w.m.Unlock()
+	})()
+
 	c := make(chan []ygs.I3BarBlock)
 
 	defer close(c)
@@ -116,23 +257,47 @@ func (w *ExecWidget) exec() error {
 			if !ok {
 				return
 			}
+
+			blocks = w.rules.apply(blocks)
+
+			if w.params.Label != "" && len(blocks) > 0 && blocks[0].FullText != "" {
+				blocks[0].FullText = w.params.Label + blocks[0].FullText
+			}
+
 			w.c <- blocks
 			w.setEnv(blocks)
+			w.last = blocks
 		}
 	})()
 
 	err = exc.Run(w.logger, c, w.params.OutputFormat)
+
+	w.m.Lock()
+	killed := w.killed
+	w.killed = false
+	w.m.Unlock()
+
+	if killed {
+		return nil
+	}
+
 	if err == nil {
-		if state := exc.ProcessState(); state != nil && state.ExitCode() != 0 {
+		if state := exc.ProcessState(); state != nil && state.ExitCode() != 0 &&
+			!(w.params.OutputFormat == executor.OutputFormatI3Blocks && state.ExitCode() == executor.I3BlocksUrgentExitCode) {
 			if w.params.Retry != nil {
 				go (func() {
-					time.Sleep(time.Second * time.Duration(*w.params.Retry))
-					w.upd <- struct{}{}
+					time.Sleep(time.Duration(*w.params.Retry))
+
+					if w.isShutdown() {
+						return
+					}
+
+					w.trigger()
 					w.resetTicker()
 				})()
 			}
 
-			if w.shutdown {
+			if w.isShutdown() {
 				return nil
 			}
 
@@ -146,11 +311,14 @@ func (w *ExecWidget) exec() error {
 // Run starts the main loop.
 func (w *ExecWidget) Run(c chan<- []ygs.I3BarBlock) error {
 	w.c = c
-	if w.params.Interval == 0 && w.signal == nil && w.params.Retry == nil {
+	if w.once() {
 		err := w.exec()
-		if w.params.Silent {
-			if err != nil {
-				w.logger.Errorf("exec failed: %s", err)
+		if err != nil && (w.params.Silent || w.params.OnError == "hide") {
+			w.logger.Errorf("exec failed: %s", err)
+
+			if !w.params.Silent {
+				w.outputWG.Wait()
+				c <- []ygs.I3BarBlock{}
 			}
 
 			return nil
@@ -159,14 +327,14 @@ func (w *ExecWidget) Run(c chan<- []ygs.I3BarBlock) error {
 		return err
 	}
 
-	if w.params.Interval > 0 {
+	if w.schedule != nil {
 		w.resetTicker()
 	}
 
-	if w.params.Interval == -1 {
+	if w.watcher != nil {
 		go (func() {
-			for {
-				w.upd <- struct{}{}
+			for range w.watcher.C {
+				w.trigger()
 			}
 		})()
 	}
@@ -178,40 +346,215 @@ func (w *ExecWidget) Run(c chan<- []ygs.I3BarBlock) error {
 		go (func() {
 			for {
 				<-sigc
-				w.upd <- struct{}{}
+				w.trigger()
 			}
 		})()
 	}
 
 	for range w.upd {
-		if err := w.exec(); err != nil {
-			if !w.params.Silent {
-				w.outputWG.Wait()
+		start := time.Now()
+		err := w.exec()
 
-				c <- []ygs.I3BarBlock{{
-					FullText: err.Error(),
-					Color:    "#ff0000",
-				}}
-			}
+		w.outputWG.Wait()
 
+		if w.params.Interval < 0 && !w.isShutdown() {
+			w.restart(err, time.Since(start))
+		}
+
+		if err != nil {
+			w.failed(err)
 			w.logger.Errorf("exec failed: %s", err)
+
+			continue
+		}
+
+		w.failures = 0
+		w.isStale = false
+
+		if w.last != nil {
+			w.good = w.last
+			w.goodTime = time.Now()
 		}
 	}
 
 	return nil
 }
 
+// restart runs the command again after exit (interval -1): immediately after a successful run,
+// not earlier than minRestartRun after the start of a short run, and with a backoff after failures.
+func (w *ExecWidget) restart(err error, elapsed time.Duration) {
+	var delay time.Duration
+
+	switch {
+	case err != nil:
+		w.restartDelay *= 2
+		if w.restartDelay < minRestartRun {
+			w.restartDelay = minRestartRun
+		}
+
+		if w.restartDelay > maxRestartDelay {
+			w.restartDelay = maxRestartDelay
+		}
+
+		delay = w.restartDelay
+	case elapsed < minRestartRun:
+		w.restartDelay = 0
+		delay = minRestartRun - elapsed
+	default:
+		w.restartDelay = 0
+	}
+
+	if delay == 0 {
+		w.trigger()
+
+		return
+	}
+
+	time.AfterFunc(delay, func() {
+		if !w.isShutdown() {
+			w.trigger()
+		}
+	})
+}
+
+// failed updates the output according to the on_error policy.
+func (w *ExecWidget) failed(err error) {
+	w.failures++
+	w.isStale = false
+
+	if w.params.Silent {
+		return
+	}
+
+	switch {
+	case w.params.OnError == "hide":
+		w.c <- []ygs.I3BarBlock{}
+	case w.params.OnError == "keep" && w.good != nil && !w.expired():
+		w.isStale = true
+		w.c <- w.stale()
+	default:
+		w.c <- []ygs.I3BarBlock{{
+			FullText: err.Error(),
+			Color:    "#ff0000",
+		}}
+	}
+}
+
+// expired reports whether the stale output should be replaced by the error.
+func (w *ExecWidget) expired() bool {
+	if w.params.MaxFailures > 0 && w.failures >= w.params.MaxFailures {
+		return true
+	}
+
+	return w.params.MaxStale > 0 && time.Since(w.goodTime) >= time.Duration(w.params.MaxStale)
+}
+
+// stale returns the last successful output with the stale templates applied.
+func (w *ExecWidget) stale() []ygs.I3BarBlock {
+	tplc := len(w.params.StaleTemplates)
+	blocks := make([]ygs.I3BarBlock, len(w.good))
+
+	for i := range w.good {
+		block := w.good[i]
+
+		// the template overrides the block fields
+		if tplc == 1 {
+			tpl := w.params.StaleTemplates[0]
+			tpl.Apply(block)
+			block = tpl
+		} else if i < tplc {
+			tpl := w.params.StaleTemplates[i]
+			tpl.Apply(block)
+			block = tpl
+		}
+
+		custom := make(map[string]ygs.Vary, len(block.Custom)+1)
+		for k, v := range block.Custom {
+			custom[k] = v
+		}
+
+		custom["_stale"] = ygs.Vary("true")
+		custom[StaleSinceField] = ygs.Vary(strconv.FormatInt(w.goodTime.Unix(), 10))
+		block.Custom = custom
+
+		blocks[i] = block
+	}
+
+	w.setEnv(blocks)
+
+	return blocks
+}
+
 // Event processes the widget events.
 func (w *ExecWidget) Event(event ygs.I3BarClickEvent, blocks []ygs.I3BarBlock) error {
 	w.setEnv(blocks)
 
+	// blocklets are executed again on click
+	if w.params.OutputFormat == executor.OutputFormatI3Blocks {
+		w.triggerEnv(executor.I3BlocksEnv(event))
+
+		return nil
+	}
+
 	if w.params.EventsUpdate {
-		w.upd <- struct{}{}
+		w.trigger()
 	}
 
 	return nil
 }
 
+// Refresh runs the command again.
+func (w *ExecWidget) Refresh() error {
+	if w.once() {
+		return errors.New("the command runs once (set interval, schedule, signal, watch or retry)")
+	}
+
+	w.trigger()
+
+	return nil
+}
+
+// trigger requests the update according to the overlap policy,
+// pending updates are merged into one.
+func (w *ExecWidget) trigger() {
+	w.triggerEnv(nil)
+}
+
+// triggerEnv requests the update with the click environment,
+// the environment is used by the next run unless the update is skipped.
+func (w *ExecWidget) triggerEnv(clickEnv []string) {
+	w.m.Lock()
+	running := w.running
+	exc := w.exc
+
+	if running && w.params.Overlap == "skip" {
+		w.m.Unlock()
+
+		return
+	}
+
+	if running && w.params.Overlap == "kill-previous" {
+		w.killed = true
+	}
+
+	if clickEnv != nil {
+		w.clickEnv = clickEnv
+	}
+	w.m.Unlock()
+
+	if running && w.params.Overlap == "kill-previous" {
+		if err := exc.Shutdown(); err != nil {
+			w.logger.Errorf("failed to kill the previous command: %s", err)
+		}
+	}
+
+	select {
+	case w.upd <- struct{}{}:
+	default:
+		// an update is already pending
+	}
+}
+
 func (w *ExecWidget) setEnv(blocks []ygs.I3BarBlock) {
 	env := make([]string, 0)
 
@@ -229,10 +572,22 @@ func (w *ExecWidget) setEnv(blocks []ygs.I3BarBlock) {
 
 // Shutdown shutdowns the widget.
 func (w *ExecWidget) Shutdown() error {
+	if w.watcher != nil {
+		_ = w.watcher.Close()
+	}
+
+	w.m.Lock()
 	w.shutdown = true
+	exc := w.exc
+
+	if w.ticker != nil {
+		w.ticker.Stop()
+		w.ticker = nil
+	}
+	w.m.Unlock()
 
-	if w.exc != nil {
-		if err := w.exc.Shutdown(); err != nil {
+	if exc != nil {
+		if err := exc.Shutdown(); err != nil {
 			return err
 		}
 	}
@@ -240,27 +595,39 @@ func (w *ExecWidget) Shutdown() error {
 	return nil
 }
 
+func (w *ExecWidget) isShutdown() bool {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	return w.shutdown
+}
+
+// once reports whether the command runs only once, blocklets run on click.
+func (w *ExecWidget) once() bool {
+	return w.params.Interval == 0 && w.schedule == nil && w.signal == nil && w.watcher == nil && w.params.Retry == nil &&
+		w.params.OutputFormat != executor.OutputFormatI3Blocks
+}
+
+// resetTicker restarts the schedule ticker, it is called by Run and by retries.
 func (w *ExecWidget) resetTicker() {
-	if w.tickerC != nil {
-		*w.tickerC <- struct{}{}
-	}
+	w.m.Lock()
+	defer w.m.Unlock()
 
-	if w.params.Interval > 0 {
-		tickerC := make(chan struct{}, 1)
-		w.tickerC = &tickerC
+	if w.shutdown {
+		return
+	}
 
-		go (func() {
-			ticker := time.NewTicker(time.Duration(w.params.Interval) * time.Second)
+	if w.ticker != nil {
+		w.ticker.Stop()
+	}
 
-			defer ticker.Stop()
+	if w.schedule != nil {
+		ticker := schedule.NewTicker(w.schedule)
+		w.ticker = ticker
 
-			for {
-				select {
-				case <-tickerC:
-					return
-				case <-ticker.C:
-					w.upd <- struct{}{}
-				}
+		go (func() {
+			for range ticker.C {
+				w.trigger()
 			}
 		})()
 	}
@@ -2,6 +2,8 @@ package widgets
 
 import (
 	"context"
+	"crypto/subtle"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,8 +11,15 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/burik666/yagostatus/pkg/schedule"
 	"github.com/burik666/yagostatus/ygs"
 
 	"golang.org/x/net/websocket"
@@ -18,9 +27,32 @@ import (
 
 // HTTPWidgetParams are widget parameters.
 type HTTPWidgetParams struct {
-	Network string
-	Listen  string
-	Path    string
+	Network string         `description:"Network: tcp or unix."`
+	Listen  string         `description:"Hostname and port or path to the socket file to bind."`
+	Path    string         `description:"Path for receiving requests (default: /<id>/)."`
+	Auth    HTTPAuthParams `description:"Authentication of requests."`
+	Origins []string       `description:"Allowed origins of browser requests (example: http://localhost:8080, * for any)."`
+	TLS     HTTPTLSParams  `yaml:"tls" description:"TLS certificate for tcp listeners."`
+
+	SocketMode  os.FileMode `yaml:"socket_mode" description:"Permissions of the socket file (example: 0660)."`
+	SocketOwner string      `yaml:"socket_owner" description:"Owner of the socket file (user name or uid)."`
+	SocketGroup string      `yaml:"socket_group" description:"Group of the socket file (group name or gid)."`
+
+	WorkDir string `description:"Working directory for relative paths."`
+
+	WidgetID string `yaml:"-"`
+}
+
+// HTTPAuthParams are authentication parameters of the http widget.
+type HTTPAuthParams struct {
+	Token     string `yaml:"token,omitempty" description:"Bearer token."`
+	TokenFile string `yaml:"token_file,omitempty" description:"File containing the bearer token."`
+}
+
+// HTTPTLSParams are TLS parameters of the http widget.
+type HTTPTLSParams struct {
+	Cert string `yaml:"cert,omitempty" description:"Certificate file (PEM)."`
+	Key  string `yaml:"key,omitempty" description:"Private key file (PEM)."`
 }
 
 // HTTPWidget implements the http server widget.
@@ -31,18 +63,27 @@ type HTTPWidget struct {
 
 	logger ygs.Logger
 
+	token    string
 	c        chan<- []ygs.I3BarBlock
 	instance *httpInstance
 
 	clients map[*websocket.Conn]chan interface{}
 	cm      sync.RWMutex
+
+	blocks []ygs.I3BarBlock
+	bm     sync.Mutex
+	expire *time.Timer
+	gen    uint64
 }
 
+// maxHTTPBodySize limits the size of request bodies.
+const maxHTTPBodySize = 1 << 20
+
 type httpInstance struct {
-	l      net.Listener
 	server *http.Server
 	mux    *http.ServeMux
 	paths  map[string]struct{}
+	params HTTPWidgetParams
 }
 
 var instances map[string]*httpInstance
@@ -73,13 +114,21 @@ func NewHTTPWidget(params interface{}, wlogger ygs.Logger) (ygs.Widget, error) {
 	}
 
 	if len(w.params.Path) == 0 {
-		return nil, errors.New("missing 'path'")
+		if len(w.params.WidgetID) == 0 {
+			return nil, errors.New("missing 'path'")
+		}
+
+		w.params.Path = "/" + w.params.WidgetID + "/"
 	}
 
 	if w.params.Network != "tcp" && w.params.Network != "unix" {
 		return nil, errors.New("invalid 'net' (may be 'tcp' or 'unix')")
 	}
 
+	if err := w.initAuth(); err != nil {
+		return nil, err
+	}
+
 	instanceKey := w.params.Listen
 	instance, ok := instances[instanceKey]
 
@@ -87,23 +136,37 @@ func NewHTTPWidget(params interface{}, wlogger ygs.Logger) (ygs.Widget, error) {
 		if _, ok := instance.paths[w.params.Path]; ok {
 			return nil, fmt.Errorf("path '%s' already in use", w.params.Path)
 		}
+
+		p := instance.params
+		if p.Network != w.params.Network ||
+			p.TLS != w.params.TLS ||
+			p.SocketMode != w.params.SocketMode ||
+			p.SocketOwner != w.params.SocketOwner ||
+			p.SocketGroup != w.params.SocketGroup {
+			return nil, fmt.Errorf("'%s' is already used with other network, tls or socket parameters", w.params.Listen)
+		}
 	} else {
 		mux := http.NewServeMux()
 		instance = &httpInstance{
-			mux:   mux,
-			paths: make(map[string]struct{}, 1),
+			mux:    mux,
+			paths:  make(map[string]struct{}, 1),
+			params: w.params,
 			server: &http.Server{
 				Addr:    w.params.Listen,
 				Handler: mux,
 			},
 		}
 
+		if err := w.initListener(instance); err != nil {
+			return nil, err
+		}
+
 		instances[w.params.Listen] = instance
 		w.instance = instance
 	}
 
 	instance.mux.HandleFunc(w.params.Path, w.httpHandler)
-	instance.paths[instanceKey] = struct{}{}
+	instance.paths[w.params.Path] = struct{}{}
 
 	w.clients = make(map[*websocket.Conn]chan interface{})
 
@@ -118,14 +181,17 @@ func (w *HTTPWidget) Run(c chan<- []ygs.I3BarBlock) error {
 		return nil
 	}
 
-	l, err := net.Listen(w.params.Network, w.params.Listen)
+	l, err := w.listen()
 	if err != nil {
 		return err
 	}
 
-	w.instance.l = l
+	if w.instance.server.TLSConfig != nil {
+		err = w.instance.server.ServeTLS(l, "", "")
+	} else {
+		err = w.instance.server.Serve(l)
+	}
 
-	err = w.instance.server.Serve(l)
 	if errors.Is(err, http.ErrServerClosed) {
 		return nil
 	}
@@ -133,16 +199,245 @@ func (w *HTTPWidget) Run(c chan<- []ygs.I3BarBlock) error {
 	return err
 }
 
+// listen creates the listener. If the socket permissions are set, the socket is created
+// in a private directory and linked to the listen path after its mode and owner are changed,
+// so it is never accessible with the default permissions.
+func (w *HTTPWidget) listen() (net.Listener, error) {
+	if w.params.Network != "unix" ||
+		(w.params.SocketMode == 0 && w.params.SocketOwner == "" && w.params.SocketGroup == "") {
+		return net.Listen(w.params.Network, w.params.Listen)
+	}
+
+	dir, err := ioutil.TempDir(filepath.Dir(w.params.Listen), ".yagostatus-")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "socket")
+
+	l, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.setSocketPermissions(tmp); err != nil {
+		l.Close()
+
+		return nil, err
+	}
+
+	// unlike rename, link fails if the path exists
+	if err := os.Link(tmp, w.params.Listen); err != nil {
+		l.Close()
+
+		return nil, err
+	}
+
+	return &socketListener{Listener: l, path: w.params.Listen}, nil
+}
+
+// socketListener removes the linked socket file on close.
+type socketListener struct {
+	net.Listener
+	path string
+}
+
+func (l *socketListener) Close() error {
+	err := l.Listener.Close()
+
+	if rerr := os.Remove(l.path); err == nil && rerr != nil && !os.IsNotExist(rerr) {
+		err = rerr
+	}
+
+	return err
+}
+
+// initAuth reads the bearer token.
+func (w *HTTPWidget) initAuth() error {
+	auth := w.params.Auth
+	if auth.Token != "" && auth.TokenFile != "" {
+		return errors.New("'auth': 'token' and 'token_file' are mutually exclusive")
+	}
+
+	w.token = auth.Token
+
+	if auth.TokenFile != "" {
+		data, err := ioutil.ReadFile(w.path(auth.TokenFile))
+		if err != nil {
+			return fmt.Errorf("'auth': %w", err)
+		}
+
+		w.token = strings.TrimSpace(string(data))
+		if w.token == "" {
+			return fmt.Errorf("'auth': empty token in '%s'", auth.TokenFile)
+		}
+	}
+
+	return nil
+}
+
+// initListener validates the listener parameters and loads the TLS certificate.
+func (w *HTTPWidget) initListener(instance *httpInstance) error {
+	if w.params.Network != "unix" &&
+		(w.params.SocketMode != 0 || w.params.SocketOwner != "" || w.params.SocketGroup != "") {
+		return errors.New("'socket_mode', 'socket_owner' and 'socket_group' require 'network: unix'")
+	}
+
+	if w.params.TLS == (HTTPTLSParams{}) {
+		return nil
+	}
+
+	if w.params.Network != "tcp" {
+		return errors.New("'tls' requires 'network: tcp'")
+	}
+
+	if w.params.TLS.Cert == "" || w.params.TLS.Key == "" {
+		return errors.New("'tls': missing 'cert' or 'key'")
+	}
+
+	cert, err := tls.LoadX509KeyPair(w.path(w.params.TLS.Cert), w.path(w.params.TLS.Key))
+	if err != nil {
+		return fmt.Errorf("'tls': %w", err)
+	}
+
+	instance.server.TLSConfig = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	return nil
+}
+
+// setSocketPermissions sets the mode and the ownership of the socket file.
+func (w *HTTPWidget) setSocketPermissions(path string) error {
+	if w.params.SocketMode != 0 {
+		if err := os.Chmod(path, w.params.SocketMode); err != nil {
+			return err
+		}
+	}
+
+	if w.params.SocketOwner == "" && w.params.SocketGroup == "" {
+		return nil
+	}
+
+	uid, gid := -1, -1
+
+	if w.params.SocketOwner != "" {
+		id := w.params.SocketOwner
+		if u, err := user.Lookup(id); err == nil {
+			id = u.Uid
+		}
+
+		n, err := strconv.Atoi(id)
+		if err != nil {
+			return fmt.Errorf("unknown user '%s'", w.params.SocketOwner)
+		}
+
+		uid = n
+	}
+
+	if w.params.SocketGroup != "" {
+		id := w.params.SocketGroup
+		if g, err := user.LookupGroup(id); err == nil {
+			id = g.Gid
+		}
+
+		n, err := strconv.Atoi(id)
+		if err != nil {
+			return fmt.Errorf("unknown group '%s'", w.params.SocketGroup)
+		}
+
+		gid = n
+	}
+
+	return os.Chown(path, uid, gid)
+}
+
+// path resolves the file path relative to the working directory.
+func (w *HTTPWidget) path(p string) string {
+	if filepath.IsAbs(p) || w.params.WorkDir == "" {
+		return p
+	}
+
+	return filepath.Join(w.params.WorkDir, p)
+}
+
+// checkOrigin rejects browser requests from origins not in the allow-list,
+// requests without the Origin header are not sent by browsers.
+func (w *HTTPWidget) checkOrigin(request *http.Request) error {
+	origin := request.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+
+	for _, o := range w.params.Origins {
+		if o == "*" || strings.EqualFold(strings.TrimRight(o, "/"), origin) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("origin '%s' is not allowed", origin)
+}
+
+// checkHost rejects tcp requests with the Host header other than localhost, an IP address
+// or the listen host, so web pages cannot reach the server by DNS rebinding.
+func (w *HTTPWidget) checkHost(request *http.Request) error {
+	if w.params.Network != "tcp" {
+		return nil
+	}
+
+	host := request.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+
+	if strings.EqualFold(host, "localhost") || net.ParseIP(host) != nil {
+		return nil
+	}
+
+	if listenHost, _, err := net.SplitHostPort(w.params.Listen); err == nil && strings.EqualFold(host, listenHost) {
+		return nil
+	}
+
+	return fmt.Errorf("host '%s' is not allowed", request.Host)
+}
+
+// authorized checks the bearer token, browsers cannot set headers for websockets,
+// so the token can also be passed as the token query parameter.
+func (w *HTTPWidget) authorized(request *http.Request) bool {
+	if w.token == "" {
+		return true
+	}
+
+	token := request.URL.Query().Get("token")
+
+	if h := request.Header.Get("Authorization"); h != "" {
+		const prefix = "Bearer "
+		if len(h) < len(prefix) || !strings.EqualFold(h[:len(prefix)], prefix) {
+			return false
+		}
+
+		token = h[len(prefix):]
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(w.token)) == 1
+}
+
 // Event processes the widget events.
 func (w *HTTPWidget) Event(event ygs.I3BarClickEvent, blocks []ygs.I3BarBlock) error {
 	return w.broadcast(event)
 }
 
 func (w *HTTPWidget) Shutdown() error {
-	if w.instance == nil || w.instance.l == nil {
+	if w.instance == nil {
 		return nil
 	}
 
+	// Serve returns immediately if the server is shut down before it is started
 	if err := w.instance.server.Shutdown(context.Background()); err != nil {
 		return err
 	}
@@ -151,43 +446,236 @@ func (w *HTTPWidget) Shutdown() error {
 }
 
 func (w *HTTPWidget) httpHandler(response http.ResponseWriter, request *http.Request) {
-	if request.Method == "GET" {
-		serv := websocket.Server{
-			Handshake: func(cfg *websocket.Config, r *http.Request) error {
-				return nil
-			},
-			Handler: w.wsHandler,
-		}
+	if err := w.checkHost(request); err != nil {
+		w.logger.Errorf("%s", err)
+		http.Error(response, err.Error(), http.StatusMisdirectedRequest)
+
+		return
+	}
 
-		serv.ServeHTTP(response, request)
+	if err := w.checkOrigin(request); err != nil {
+		w.logger.Errorf("%s", err)
+		http.Error(response, err.Error(), http.StatusForbidden)
 
 		return
 	}
 
-	if request.Method == "POST" {
-		body, err := ioutil.ReadAll(request.Body)
+	if !w.authorized(request) {
+		response.Header().Set("WWW-Authenticate", `Bearer realm="yagostatus"`)
+		http.Error(response, "unauthorized", http.StatusUnauthorized)
+
+		return
+	}
+
+	switch request.Method {
+	case http.MethodGet:
+		if strings.EqualFold(request.Header.Get("Upgrade"), "websocket") {
+			serv := websocket.Server{
+				Handshake: func(cfg *websocket.Config, r *http.Request) error {
+					return w.checkOrigin(r)
+				},
+				Handler: w.wsHandler,
+			}
+
+			serv.ServeHTTP(response, request)
+
+			return
+		}
+
+		w.bm.Lock()
+		blocks := append([]ygs.I3BarBlock{}, w.blocks...)
+		w.bm.Unlock()
+
+		response.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(response).Encode(blocks); err != nil {
+			w.logger.Errorf("failed to write response: %s", err)
+		}
+
+	case http.MethodPost, http.MethodPut:
+		ttl, err := parseTTL(request)
+		if err != nil {
+			w.httpError(response, http.StatusBadRequest, err)
+
+			return
+		}
+
+		var blocks []ygs.I3BarBlock
+		if status, err := readJSON(response, request, &blocks); err != nil {
+			w.httpError(response, status, err)
+
+			return
+		}
+
+		w.update(blocks, ttl)
+		response.WriteHeader(http.StatusNoContent)
+
+	case http.MethodPatch:
+		ttl, err := parseTTL(request)
 		if err != nil {
-			w.logger.Errorf("%s", err)
+			w.httpError(response, http.StatusBadRequest, err)
+
+			return
 		}
 
-		var messages []ygs.I3BarBlock
-		if err := json.Unmarshal(body, &messages); err != nil {
-			w.logger.Errorf("%s", err)
-			response.WriteHeader(http.StatusBadRequest)
-			fmt.Fprintf(response, "%s", err)
+		var fields json.RawMessage
+		if status, err := readJSON(response, request, &fields); err != nil {
+			w.httpError(response, status, err)
+
+			return
 		}
 
-		w.c <- messages
+		status, err := w.patch(request, fields, ttl)
+		if err != nil {
+			w.httpError(response, status, err)
+
+			return
+		}
+
+		response.WriteHeader(http.StatusNoContent)
+
+	case http.MethodDelete:
+		w.update(nil, 0)
+		response.WriteHeader(http.StatusNoContent)
 
+	default:
+		response.Header().Set("Allow", "GET, POST, PUT, PATCH, DELETE")
+		http.Error(response, "bad request method, allow GET, POST, PUT, PATCH and DELETE", http.StatusMethodNotAllowed)
+	}
+}
+
+// update replaces the widget blocks, the blocks are cleared after ttl (if positive).
+func (w *HTTPWidget) update(blocks []ygs.I3BarBlock, ttl time.Duration) {
+	if blocks == nil {
+		blocks = []ygs.I3BarBlock{}
+	}
+
+	w.bm.Lock()
+	defer w.bm.Unlock()
+
+	w.resetExpire(ttl)
+	w.setBlocks(blocks)
+}
+
+// setBlocks sends the blocks, w.bm must be held.
+func (w *HTTPWidget) setBlocks(blocks []ygs.I3BarBlock) {
+	w.blocks = blocks
+	w.c <- blocks
+}
+
+// resetExpire stops the expiry timer and starts a new one if ttl is positive, w.bm must be held.
+func (w *HTTPWidget) resetExpire(ttl time.Duration) {
+	if w.expire != nil {
+		w.expire.Stop()
+		w.expire = nil
+	}
+
+	w.gen++
+
+	if ttl <= 0 {
 		return
 	}
 
-	response.WriteHeader(http.StatusBadRequest)
+	gen := w.gen
+	w.expire = time.AfterFunc(ttl, func() {
+		w.bm.Lock()
+		defer w.bm.Unlock()
+
+		// the blocks were updated after the timer fired
+		if w.gen != gen {
+			return
+		}
+
+		w.expire = nil
+		w.setBlocks([]ygs.I3BarBlock{})
+	})
+}
+
+// patch merges the fields into the blocks selected by the index and name query parameters,
+// all blocks are selected without parameters. The expiry timer is kept without ttl.
+func (w *HTTPWidget) patch(request *http.Request, fields json.RawMessage, ttl time.Duration) (int, error) {
+	query := request.URL.Query()
+	name := query.Get("name")
+	index := -1
+
+	if v := query.Get("index"); v != "" {
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 0 {
+			return http.StatusBadRequest, fmt.Errorf("invalid index '%s'", v)
+		}
+
+		index = i
+	}
+
+	w.bm.Lock()
+	defer w.bm.Unlock()
+
+	blocks := append([]ygs.I3BarBlock{}, w.blocks...)
+	matched := false
+
+	for i := range blocks {
+		if (index >= 0 && i != index) || (name != "" && blocks[i].Name != name) {
+			continue
+		}
 
-	_, err := response.Write([]byte("bad request method, allow GET for websocket and POST for HTTP update"))
+		if err := blocks[i].FromJSON(fields, true); err != nil {
+			return http.StatusBadRequest, err
+		}
+
+		matched = true
+	}
+
+	if !matched {
+		return http.StatusNotFound, errors.New("no matching blocks")
+	}
+
+	if ttl > 0 {
+		w.resetExpire(ttl)
+	}
+
+	w.setBlocks(blocks)
+
+	return http.StatusNoContent, nil
+}
+
+// readJSON decodes the request body.
+func readJSON(response http.ResponseWriter, request *http.Request, v interface{}) (int, error) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(response, request.Body, maxHTTPBodySize))
 	if err != nil {
-		w.logger.Errorf("failed to write response: %s", err)
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return http.StatusRequestEntityTooLarge, err
+		}
+
+		return http.StatusBadRequest, err
 	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	return http.StatusOK, nil
+}
+
+// parseTTL parses the ttl query parameter, seconds or a duration string.
+func parseTTL(request *http.Request) (time.Duration, error) {
+	v := request.URL.Query().Get("ttl")
+	if v == "" {
+		return 0, nil
+	}
+
+	ttl, err := schedule.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ttl: %w", err)
+	}
+
+	return time.Duration(ttl), nil
+}
+
+// httpError logs the error and responds with the status.
+func (w *HTTPWidget) httpError(response http.ResponseWriter, status int, err error) {
+	w.logger.Errorf("%s", err)
+	http.Error(response, err.Error(), status)
 }
 
 func (w *HTTPWidget) wsHandler(ws *websocket.Conn) {
@@ -195,11 +683,9 @@ func (w *HTTPWidget) wsHandler(ws *websocket.Conn) {
 
 	ch := make(chan interface{})
 
-	w.cm.RLock()
+	w.cm.Lock()
 	w.clients[ws] = ch
-	w.cm.RUnlock()
-
-	var blocks []ygs.I3BarBlock
+	w.cm.Unlock()
 
 	go func() {
 		for {
@@ -215,6 +701,8 @@ func (w *HTTPWidget) wsHandler(ws *websocket.Conn) {
 	}()
 
 	for {
+		var blocks []ygs.I3BarBlock
+
 		if err := websocket.JSON.Receive(ws, &blocks); err != nil {
 			if errors.Is(err, io.EOF) {
 				break
@@ -225,7 +713,7 @@ func (w *HTTPWidget) wsHandler(ws *websocket.Conn) {
 			break
 		}
 
-		w.c <- blocks
+		w.update(blocks, 0)
 	}
 
 	w.cm.Lock()
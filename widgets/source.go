@@ -0,0 +1,163 @@
+package widgets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/burik666/yagostatus/internal/source"
+	"github.com/burik666/yagostatus/ygs"
+)
+
+// SourceWidgetParams are widget parameters.
+type SourceWidgetParams struct {
+	Blocks ygs.I3BarBlocks `description:"List of i3bar blocks with {{source.path}} placeholders."`
+	Path   string          `description:"Path to the blocks in the source output (source.path), instead of blocks."`
+}
+
+// SourceWidget implements the source widget.
+type SourceWidget struct {
+	ygs.BlankWidget
+
+	params SourceWidgetParams
+
+	logger ygs.Logger
+
+	sources []*source.Source
+}
+
+func init() {
+	if err := ygs.RegisterWidget(ygs.WidgetSpec{
+		Name:          "source",
+		NewFunc:       NewSourceWidget,
+		DefaultParams: SourceWidgetParams{},
+	}); err != nil {
+		panic(err)
+	}
+}
+
+// NewSourceWidget returns a new SourceWidget.
+func NewSourceWidget(params interface{}, wlogger ygs.Logger) (ygs.Widget, error) {
+	w := &SourceWidget{
+		params: params.(SourceWidgetParams),
+		logger: wlogger,
+	}
+
+	var names []string
+
+	switch {
+	case w.params.Blocks != nil && w.params.Path != "":
+		return nil, errors.New("'blocks' and 'path' are mutually exclusive")
+	case w.params.Blocks != nil:
+		names = source.BlocksReferences(w.params.Blocks)
+	case w.params.Path != "":
+		names = source.References("{{" + w.params.Path + "}}")
+	default:
+		return nil, errors.New("missing 'blocks' or 'path'")
+	}
+
+	if len(names) == 0 {
+		return nil, errors.New("no sources are referenced")
+	}
+
+	for _, name := range names {
+		s, ok := source.Get(name)
+		if !ok {
+			return nil, fmt.Errorf("source '%s' not found", name)
+		}
+
+		w.sources = append(w.sources, s)
+	}
+
+	return w, nil
+}
+
+// Run renders the blocks when the sources are updated.
+func (w *SourceWidget) Run(c chan<- []ygs.I3BarBlock) error {
+	cases := make([]reflect.SelectCase, len(w.sources))
+
+	for i, s := range w.sources {
+		cases[i] = reflect.SelectCase{
+			Dir:  reflect.SelectRecv,
+			Chan: reflect.ValueOf(s.Subscribe()),
+		}
+	}
+
+	for {
+		if w.ready() {
+			blocks, err := w.render()
+			if err != nil {
+				w.logger.Errorf("%s", err)
+
+				blocks = []ygs.I3BarBlock{{
+					FullText: err.Error(),
+					Color:    "#ff0000",
+				}}
+			}
+
+			c <- blocks
+		}
+
+		reflect.Select(cases)
+	}
+}
+
+// Refresh updates the sources.
+func (w *SourceWidget) Refresh() error {
+	for _, s := range w.sources {
+		s.Refresh()
+	}
+
+	return nil
+}
+
+// ready reports whether all sources have data.
+func (w *SourceWidget) ready() bool {
+	for _, s := range w.sources {
+		if _, ok := s.Value(); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (w *SourceWidget) render() ([]ygs.I3BarBlock, error) {
+	if w.params.Path != "" {
+		v, ok := source.Lookup(w.params.Path)
+		if !ok {
+			return nil, fmt.Errorf("path '%s' not found", w.params.Path)
+		}
+
+		// a single block or a list of blocks
+		if _, ok := v.(map[string]interface{}); ok {
+			v = []interface{}{v}
+		}
+
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+
+		var blocks []ygs.I3BarBlock
+		if err := json.Unmarshal(b, &blocks); err != nil {
+			return nil, fmt.Errorf("path '%s': invalid blocks: %w", w.params.Path, err)
+		}
+
+		return blocks, nil
+	}
+
+	blocks := make([]ygs.I3BarBlock, len(w.params.Blocks))
+
+	for i := range w.params.Blocks {
+		b, err := source.RenderBlock(w.params.Blocks[i])
+		if err != nil {
+			return nil, err
+		}
+
+		blocks[i] = b
+	}
+
+	return blocks, nil
+}
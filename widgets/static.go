@@ -1,7 +1,6 @@
 package widgets
 
 import (
-	"encoding/json"
 	"errors"
 
 	"github.com/burik666/yagostatus/ygs"
@@ -9,7 +8,7 @@ import (
 
 // StaticWidgetParams are widget parameters.
 type StaticWidgetParams struct {
-	Blocks string
+	Blocks ygs.I3BarBlocks `description:"List of i3bar blocks."`
 }
 
 // StaticWidget implements a static widget.
@@ -37,13 +36,11 @@ func NewStaticWidget(params interface{}, wlogger ygs.Logger) (ygs.Widget, error)
 		params: params.(StaticWidgetParams),
 	}
 
-	if len(w.params.Blocks) == 0 {
+	if w.params.Blocks == nil {
 		return nil, errors.New("missing 'blocks'")
 	}
 
-	if err := json.Unmarshal([]byte(w.params.Blocks), &w.blocks); err != nil {
-		return nil, err
-	}
+	w.blocks = w.params.Blocks
 
 	return w, nil
 }
@@ -5,19 +5,30 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/burik666/yagostatus/pkg/executor"
+	"github.com/burik666/yagostatus/pkg/schedule"
 	"github.com/burik666/yagostatus/ygs"
 )
 
 // WrapperWidgetParams are widget parameters.
 type WrapperWidgetParams struct {
-	Command string
-	WorkDir string
-	Env     []string
+	Command       executor.Command  `description:"Command to execute: a command line (split into arguments without a shell) or a list of arguments."`
+	WorkDir       string            `description:"Working directory."`
+	Env           []string          `description:"Environment variables."`
+	Restart       bool              `description:"Restart the command if it exits, the last output is kept while restarting."`
+	RestartDelay  schedule.Duration `yaml:"restart_delay" description:"Delay before the restart, doubled after each restart up to a minute."`
+	MaxRestarts   int               `yaml:"max_restarts" description:"Give up if the command is restarted more than N times within restart_window (0 for no limit)."`
+	RestartWindow schedule.Duration `yaml:"restart_window" description:"Time window of max_restarts, the delay is reset if the command runs longer."`
+	BlockRules    `yaml:",inline"`
 }
 
+// maxRestartDelay limits the restart backoff.
+const maxRestartDelay = time.Minute
+
 // WrapperWidget implements the wrapper of other status commands.
 type WrapperWidget struct {
 	ygs.BlankWidget
@@ -26,18 +37,36 @@ type WrapperWidget struct {
 
 	logger ygs.Logger
 
+	args  []string
+	rules *blockRules
+
+	m     sync.Mutex
 	exc   *executor.Executor
-	stdin io.WriteCloser
+	stdin *wrapperStdin
+	done  chan struct{}
+
+	stopped  bool
+	shutdown bool
+}
+
+// wrapperStdin is the stdin of a process, writes may block and are not done under WrapperWidget.m.
+type wrapperStdin struct {
+	m sync.Mutex
+	w io.Writer
 
 	eventBracketWritten bool
-	shutdown            bool
 }
 
 func init() {
 	if err := ygs.RegisterWidget(ygs.WidgetSpec{
-		Name:          "wrapper",
-		NewFunc:       NewWrapperWidget,
-		DefaultParams: WrapperWidgetParams{},
+		Name:    "wrapper",
+		NewFunc: NewWrapperWidget,
+		DefaultParams: WrapperWidgetParams{
+			Restart:       true,
+			RestartDelay:  schedule.Duration(time.Second),
+			MaxRestarts:   5,
+			RestartWindow: schedule.Duration(time.Minute),
+		},
 	}); err != nil {
 		panic(err)
 	}
@@ -48,65 +77,168 @@ func NewWrapperWidget(params interface{}, wlogger ygs.Logger) (ygs.Widget, error
 	w := &WrapperWidget{
 		params: params.(WrapperWidgetParams),
 		logger: wlogger,
+		done:   make(chan struct{}),
 	}
 
-	if len(w.params.Command) == 0 {
+	if w.params.Command.IsEmpty() {
 		return nil, errors.New("missing 'command'")
 	}
 
-	exc, err := executor.Exec(w.params.Command)
+	args, err := w.params.Command.Args(executor.ShellNone)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("command: %w", err)
 	}
 
-	exc.SetWD(w.params.WorkDir)
+	if w.params.RestartDelay < 0 || w.params.RestartWindow < 0 || w.params.MaxRestarts < 0 {
+		return nil, errors.New("restart_delay, max_restarts and restart_window should be positive")
+	}
 
-	exc.AddEnv(w.params.Env...)
+	w.args = args
 
-	w.exc = exc
+	if w.rules, err = newBlockRules(w.params.BlockRules); err != nil {
+		return nil, err
+	}
 
 	return w, nil
 }
 
-// Run starts the main loop.
+// Run starts the command and restarts it if it exits.
 func (w *WrapperWidget) Run(c chan<- []ygs.I3BarBlock) error {
-	var err error
+	var restarts []time.Time
+
+	window := time.Duration(w.params.RestartWindow)
+	delay := time.Duration(w.params.RestartDelay)
+
+	for {
+		started := time.Now()
+
+		err := w.run(c)
+		if w.isShutdown() {
+			return nil
+		}
 
-	w.stdin, err = w.exc.Stdin()
+		if !w.params.Restart {
+			return err
+		}
+
+		now := time.Now()
+
+		// the command was running long enough, it is not a crash loop
+		if now.Sub(started) >= window {
+			delay = time.Duration(w.params.RestartDelay)
+		}
+
+		recent := restarts[:0]
+		for _, t := range restarts {
+			if now.Sub(t) < window {
+				recent = append(recent, t)
+			}
+		}
+
+		restarts = append(recent, now)
+
+		if w.params.MaxRestarts > 0 && len(restarts) > w.params.MaxRestarts {
+			return fmt.Errorf("%w (restarted %d times within %s, giving up)", err, w.params.MaxRestarts, w.params.RestartWindow)
+		}
+
+		w.logger.Errorf("%s, restarting in %s", err, delay)
+
+		select {
+		case <-w.done:
+			return nil
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > maxRestartDelay {
+			delay = maxRestartDelay
+		}
+	}
+}
+
+// run starts a new process, the header and click events are negotiated again.
+func (w *WrapperWidget) run(c chan<- []ygs.I3BarBlock) error {
+	exc, err := executor.ExecArgv(w.args)
 	if err != nil {
 		return err
 	}
 
-	defer w.stdin.Close()
+	exc.SetWD(w.params.WorkDir)
+
+	exc.AddEnv(w.params.Env...)
 
-	err = w.exc.Run(w.logger, c, executor.OutputFormatJSON)
-	if err == nil {
-		if w.shutdown {
-			return nil
+	stdin, err := exc.Stdin()
+	if err != nil {
+		return err
+	}
+
+	defer stdin.Close()
+
+	// the stopped state is applied again to the restarted process
+	exc.OnHeader(func(header *ygs.I3BarHeader) {
+		w.m.Lock()
+		defer w.m.Unlock()
+
+		if w.stopped {
+			if err := exc.Signal(stopSignal(header)); err != nil {
+				w.logger.Errorf("stop: %s", err)
+			}
 		}
+	})
 
-		if state := w.exc.ProcessState(); state != nil {
-			return fmt.Errorf("process exited unexpectedly: %s", state.String())
+	w.m.Lock()
+	w.exc = exc
+	w.stdin = &wrapperStdin{w: stdin}
+	w.m.Unlock()
+
+	out := make(chan []ygs.I3BarBlock)
+	outDone := make(chan struct{})
+
+	go func() {
+		defer close(outDone)
+
+		for blocks := range out {
+			c <- w.rules.apply(blocks)
 		}
+	}()
+
+	err = exc.Run(w.logger, out, executor.OutputFormatJSON)
+
+	close(out)
+	<-outDone
+
+	state := exc.ProcessState()
+
+	switch {
+	case err == nil && state != nil:
+		return fmt.Errorf("process exited unexpectedly: %s", state.String())
+	case err == nil:
+		return errors.New("process exited unexpectedly")
+	case state != nil:
+		return fmt.Errorf("%w (process %s)", err, state.String())
 	}
 
 	return err
 }
 
+func (w *WrapperWidget) isShutdown() bool {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	return w.shutdown
+}
+
 // Event processes the widget events.
 func (w *WrapperWidget) Event(event ygs.I3BarClickEvent, blocks []ygs.I3BarBlock) error {
-	if w.stdin == nil {
+	w.m.Lock()
+	exc, stdin := w.exc, w.stdin
+	w.m.Unlock()
+
+	if stdin == nil {
 		return nil
 	}
 
-	if header := w.exc.I3BarHeader(); header != nil && header.ClickEvents {
-		if !w.eventBracketWritten {
-			w.eventBracketWritten = true
-			if _, err := w.stdin.Write([]byte("[")); err != nil {
-				return err
-			}
-		}
-
+	if header := exc.I3BarHeader(); header != nil && header.ClickEvents {
 		msg, err := json.Marshal(event)
 		if err != nil {
 			return err
@@ -114,7 +246,17 @@ func (w *WrapperWidget) Event(event ygs.I3BarClickEvent, blocks []ygs.I3BarBlock
 
 		msg = append(msg, []byte(",\n")...)
 
-		if _, err := w.stdin.Write(msg); err != nil {
+		stdin.m.Lock()
+		defer stdin.m.Unlock()
+
+		if !stdin.eventBracketWritten {
+			stdin.eventBracketWritten = true
+			if _, err := stdin.w.Write([]byte("[")); err != nil {
+				return err
+			}
+		}
+
+		if _, err := stdin.w.Write(msg); err != nil {
 			return err
 		}
 	}
@@ -122,19 +264,40 @@ func (w *WrapperWidget) Event(event ygs.I3BarClickEvent, blocks []ygs.I3BarBlock
 	return nil
 }
 
+// stopSignal returns the stop signal of the header, SIGSTOP by default.
+func stopSignal(header *ygs.I3BarHeader) syscall.Signal {
+	if header != nil && header.StopSignal != 0 {
+		return syscall.Signal(header.StopSignal)
+	}
+
+	return syscall.SIGSTOP
+}
+
 // Stop stops the widdget.
 func (w *WrapperWidget) Stop() error {
-	if header := w.exc.I3BarHeader(); header != nil {
-		if header.StopSignal != 0 {
-			return w.exc.Signal(syscall.Signal(header.StopSignal))
-		}
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	w.stopped = true
+
+	if w.exc == nil {
+		return nil
 	}
 
-	return w.exc.Signal(syscall.SIGSTOP)
+	return w.exc.Signal(stopSignal(w.exc.I3BarHeader()))
 }
 
 // Continue continues the widdget.
 func (w *WrapperWidget) Continue() error {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	w.stopped = false
+
+	if w.exc == nil {
+		return nil
+	}
+
 	if header := w.exc.I3BarHeader(); header != nil {
 		if header.ContSignal != 0 {
 			return w.exc.Signal(syscall.Signal(header.ContSignal))
@@ -146,7 +309,13 @@ func (w *WrapperWidget) Continue() error {
 
 // Shutdown shutdowns the widget.
 func (w *WrapperWidget) Shutdown() error {
-	w.shutdown = true
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	if !w.shutdown {
+		w.shutdown = true
+		close(w.done)
+	}
 
 	if w.exc != nil {
 		if err := w.exc.Shutdown(); err != nil {
@@ -12,19 +12,20 @@ import (
 	"os/exec"
 	"reflect"
 	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/burik666/yagostatus/internal/config"
 	"github.com/burik666/yagostatus/internal/registry"
+	"github.com/burik666/yagostatus/internal/source"
 	"github.com/burik666/yagostatus/pkg/executor"
+	"github.com/burik666/yagostatus/widgets"
 	"github.com/burik666/yagostatus/ygs"
 
 	_ "github.com/burik666/yagostatus/plugins"
-	_ "github.com/burik666/yagostatus/widgets"
 
 	"go.i3wm.org/i3/v4"
 )
@@ -32,7 +33,10 @@ import (
 type widgetContainer struct {
 	instance ygs.Widget
 	output   []ygs.I3BarBlock
+	raw      []ygs.I3BarBlock
 	config   config.WidgetConfig
+	filters  []ygs.Filter
+	sources  []string // sources referenced by templates
 	ch       chan []ygs.I3BarBlock
 	logger   ygs.Logger
 	m        sync.RWMutex
@@ -41,6 +45,9 @@ type widgetContainer struct {
 // YaGoStatus is the main struct.
 type YaGoStatus struct {
 	widgets []widgetContainer
+	filters []ygs.Filter
+	// ids maps widget ids to indexes
+	ids map[string]int
 
 	upd chan int
 
@@ -59,6 +66,7 @@ func NewYaGoStatus(cfg config.Config, sway bool, l ygs.Logger) *YaGoStatus {
 		cfg:    cfg,
 		sway:   sway,
 		logger: l,
+		ids:    make(map[string]int),
 	}
 
 	if sway {
@@ -81,6 +89,28 @@ func NewYaGoStatus(cfg config.Config, sway bool, l ygs.Logger) *YaGoStatus {
 		}
 	}
 
+	names := make([]string, 0, len(cfg.Sources))
+	for name := range cfg.Sources {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := source.Register(name, cfg.Sources[name], l.WithPrefix(fmt.Sprintf("[source %s]", name))); err != nil {
+			l.Errorf("Failed to register source: %s", err)
+			status.errorWidget(err.Error())
+		}
+	}
+
+	filters, err := newFilters(cfg.Output.Filters, l)
+	if err != nil {
+		l.Errorf("Failed to create output filters: %s", err)
+		status.errorWidget("output." + err.Error())
+	}
+
+	status.filters = filters
+
 	for wi := range cfg.Widgets {
 		status.addWidget(cfg.Widgets[wi])
 	}
@@ -95,32 +125,53 @@ func (status *YaGoStatus) errorWidget(text string) {
 func (status *YaGoStatus) addWidget(wcfg config.WidgetConfig) {
 	wlogger := status.logger.WithPrefix(fmt.Sprintf("[%s#%d]", wcfg.File, wcfg.Index+1))
 
+	// the error widget keeps the id, so events can target it
+	errorWidget := func(text string) {
+		ew := config.ErrorWidget(text)
+		ew.ID = wcfg.ID
+		status.addWidget(ew)
+	}
+
 	(func() {
 		defer (func() {
 			if r := recover(); r != nil {
 				wlogger.Errorf("NewWidget panic: %s", r)
 				debug.PrintStack()
-				status.errorWidget("widget panic")
+				errorWidget("widget panic")
 			}
 		})()
 
 		widget, err := registry.NewWidget(wcfg, wlogger)
 		if err != nil {
 			wlogger.Errorf("Failed to create widget: %s", err)
-			status.errorWidget(err.Error())
+			errorWidget(err.Error())
+
+			return
+		}
+
+		filters, err := newFilters(wcfg.Filters, wlogger)
+		if err != nil {
+			wlogger.Errorf("Failed to create widget filters: %s", err)
+			errorWidget(err.Error())
 
 			return
 		}
 
+		if wcfg.ID != "" {
+			status.ids[wcfg.ID] = len(status.widgets)
+		}
+
 		status.widgets = append(status.widgets, widgetContainer{
 			instance: widget,
 			config:   wcfg,
+			filters:  filters,
+			sources:  source.Declared(wcfg.Templates),
 			logger:   wlogger,
 		})
 	})()
 }
 
-func (status *YaGoStatus) processWidgetEvents(wi int, block ygs.I3BarBlock, event ygs.I3BarClickEvent) error {
+func (status *YaGoStatus) processWidgetEvents(wi int, bi int, block ygs.I3BarBlock, event ygs.I3BarClickEvent) error {
 	defer (func() {
 		if r := recover(); r != nil {
 			status.widgets[wi].logger.Errorf("widget event panic: %s", r)
@@ -149,6 +200,19 @@ func (status *YaGoStatus) processWidgetEvents(wi int, block ygs.I3BarBlock, even
 			(widgetEvent.Name == "" || widgetEvent.Name == event.Name) &&
 			(widgetEvent.Instance == "" || widgetEvent.Instance == event.Instance) &&
 			checkModifiers(widgetEvent.Modifiers, event.Modifiers) {
+			if widgetEvent.Action != "" {
+				if err := status.runAction(actionEvent{
+					wi:    wi,
+					bi:    bi,
+					block: block,
+					event: event,
+				}, widgetEvent.Action); err != nil {
+					return err
+				}
+
+				continue
+			}
+
 			exc, err := executor.Exec("sh", "-c", widgetEvent.Command)
 			if err != nil {
 				return err
@@ -169,11 +233,13 @@ func (status *YaGoStatus) processWidgetEvents(wi int, block ygs.I3BarBlock, even
 				fmt.Sprintf("I3_%s=%d", "WIDTH", event.Width),
 				fmt.Sprintf("I3_%s=%d", "HEIGHT", event.Height),
 				fmt.Sprintf("I3_%s=%s", "MODIFIERS", strings.Join(event.Modifiers, ",")),
+				fmt.Sprintf("I3_%s=%s", "WIDGET_ID", status.widgets[wi].config.ID),
 			)
 
 			exc.AddEnv(widgetEvent.Env...)
 
 			exc.AddEnv(block.Env("")...)
+			exc.AddEnv(staleEnv(block)...)
 
 			stdin, err := exc.Stdin()
 			if err != nil {
@@ -197,16 +263,28 @@ func (status *YaGoStatus) processWidgetEvents(wi int, block ygs.I3BarBlock, even
 				return err
 			}
 
+			ch := status.widgets[wi].ch
+
+			if widgetEvent.Target != "" {
+				ti, ok := status.ids[widgetEvent.Target]
+				if !ok {
+					return fmt.Errorf("target '%s' not found", widgetEvent.Target)
+				}
+
+				ch = status.widgets[ti].ch
+			}
+
 			err = exc.Run(
 				status.widgets[wi].logger,
-				status.widgets[wi].ch,
+				ch,
 				executor.OutputFormat(widgetEvent.OutputFormat),
 			)
 			if err != nil {
 				return err
 			}
 
-			if state := exc.ProcessState(); state != nil && state.ExitCode() != 0 {
+			if state := exc.ProcessState(); state != nil && state.ExitCode() != 0 &&
+				!(widgetEvent.OutputFormat == string(executor.OutputFormatI3Blocks) && state.ExitCode() == executor.I3BlocksUrgentExitCode) {
 				return fmt.Errorf("process exited unexpectedly: %s", state.String())
 			}
 		}
@@ -222,20 +300,45 @@ func (status *YaGoStatus) addWidgetOutput(wi int, blocks []ygs.I3BarBlock) {
 	for blockIndex := range blocks {
 		block := blocks[blockIndex]
 
+		tpli := -1
 		if tplc == 1 {
-			block.Apply(status.widgets[wi].config.Templates[0])
+			tpli = 0
 		} else if blockIndex < tplc {
-			block.Apply(status.widgets[wi].config.Templates[blockIndex])
+			tpli = blockIndex
 		}
 
-		block.Name = fmt.Sprintf("yagostatus-%d-%s", wi, block.Name)
-		block.Instance = fmt.Sprintf("yagostatus-%d-%d-%s", wi, blockIndex, block.Instance)
+		if tpli >= 0 {
+			tpl := status.widgets[wi].config.Templates[tpli]
+
+			if len(status.widgets[wi].sources) > 0 {
+				rtpl, err := source.RenderBlock(tpl)
+				if err != nil {
+					status.widgets[wi].logger.Errorf("Failed to render template: %s", err)
+				} else {
+					tpl = rtpl
+				}
+			}
+
+			block.Apply(tpl)
+		}
 
 		output[blockIndex] = block
 	}
 
+	output = status.filterWidget(wi, output)
+
+	for blockIndex := range output {
+		block := &output[blockIndex]
+		block.Name = fmt.Sprintf("yagostatus-%d-%s", wi, block.Name)
+		block.Instance = fmt.Sprintf("yagostatus-%d-%d-%s", wi, blockIndex, block.Instance)
+	}
+
+	raw := make([]ygs.I3BarBlock, len(blocks))
+	copy(raw, blocks)
+
 	status.widgets[wi].m.Lock()
 	status.widgets[wi].output = output
+	status.widgets[wi].raw = raw
 	status.widgets[wi].m.Unlock()
 
 	status.upd <- wi
@@ -266,6 +369,11 @@ func (status *YaGoStatus) eventReader() error {
 			continue
 		}
 
+		// separators and blocks added by filters
+		if event.Name == separatorName || !strings.HasPrefix(event.Name, "yagostatus-") {
+			continue
+		}
+
 		go func(event ygs.I3BarClickEvent) {
 			wi, name, err := splitName(event.Name)
 			if err != nil {
@@ -276,7 +384,14 @@ func (status *YaGoStatus) eventReader() error {
 
 			_, oi, instance, err := splitInstance(event.Instance)
 			if err != nil {
-				status.logger.Errorf("failed to parse event instance '%s': %s", event.Name, err)
+				status.logger.Errorf("failed to parse event instance '%s': %s", event.Instance, err)
+
+				return
+			}
+
+			// blocks of filters may have any name
+			if wi < 0 || wi >= len(status.widgets) || oi < 0 {
+				status.logger.Errorf("unknown event block '%s' '%s'", event.Name, event.Instance)
 
 				return
 			}
@@ -286,7 +401,7 @@ func (status *YaGoStatus) eventReader() error {
 			e.Instance = instance
 
 			status.widgets[wi].m.RLock()
-			if len(status.widgets[wi].output) < oi {
+			if len(status.widgets[wi].output) <= oi {
 				status.widgets[wi].m.RUnlock()
 
 				return
@@ -299,7 +414,7 @@ func (status *YaGoStatus) eventReader() error {
 				block.Name = e.Name
 				block.Instance = e.Instance
 
-				if err := status.processWidgetEvents(wi, block, e); err != nil {
+				if err := status.processWidgetEvents(wi, oi, block, e); err != nil {
 					status.widgets[wi].logger.Errorf("event error: %s", err)
 
 					status.widgets[wi].ch <- []ygs.I3BarBlock{{
@@ -337,8 +452,6 @@ func (status *YaGoStatus) Run() error {
 
 	widgetChans := make([]reflect.SelectCase, len(status.widgets))
 
-	var wcounter int32 = int32(len(status.widgets))
-
 	for wi := range status.widgets {
 		status.widgets[wi].ch = make(chan []ygs.I3BarBlock)
 
@@ -349,7 +462,6 @@ func (status *YaGoStatus) Run() error {
 
 		go func(wi int) {
 			defer (func() {
-				atomic.AddInt32(&wcounter, -1)
 				if r := recover(); r != nil {
 					status.widgets[wi].logger.Errorf("widget panic: %s", r)
 					debug.PrintStack()
@@ -370,8 +482,33 @@ func (status *YaGoStatus) Run() error {
 		}(wi)
 	}
 
+	for wi := range status.widgets {
+		for _, name := range status.widgets[wi].sources {
+			s, ok := source.Get(name)
+			if !ok {
+				continue
+			}
+
+			// templates are rendered again when the source is updated
+			go func(wi int, c <-chan struct{}) {
+				for range c {
+					status.widgets[wi].m.RLock()
+					raw := status.widgets[wi].raw
+					status.widgets[wi].m.RUnlock()
+
+					if raw != nil {
+						status.widgets[wi].ch <- raw
+					}
+				}
+			}(wi, s.Subscribe())
+		}
+	}
+
+	source.Start()
+
 	go func() {
-		for wcounter > 0 {
+		// widgets can be updated by events after their Run is done
+		for {
 			wi, out, _ := reflect.Select(widgetChans)
 			status.addWidgetOutput(wi, out.Interface().([]ygs.I3BarBlock))
 		}
@@ -393,16 +530,20 @@ func (status *YaGoStatus) Run() error {
 
 	go func() {
 		for range status.upd {
-			var result []ygs.I3BarBlock
+			var outputs [][]ygs.I3BarBlock
 
 			for wi := range status.widgets {
 				if checkWorkspaceConditions(status.widgets[wi].config.Workspaces, status.visibleWorkspaces) {
 					status.widgets[wi].m.RLock()
-					result = append(result, status.widgets[wi].output...)
+					if len(status.widgets[wi].output) > 0 {
+						outputs = append(outputs, status.widgets[wi].output)
+					}
 					status.widgets[wi].m.RUnlock()
 				}
 			}
 
+			result := status.filterFrame(status.joinOutputs(outputs))
+
 			fmt.Print(",")
 
 			if result == nil {
@@ -462,6 +603,8 @@ func (status *YaGoStatus) Shutdown() {
 	}
 
 	wg.Wait()
+
+	source.Shutdown()
 }
 
 // Stop stops widgets and main loop.
@@ -582,6 +725,9 @@ func checkWorkspaceConditions(conditions []string, values []string) bool {
 
 func splitName(name string) (int, string, error) {
 	parts := strings.SplitN(name, "-", 3)
+	if len(parts) != 3 {
+		return 0, "", errors.New("invalid format")
+	}
 
 	wi, err := strconv.ParseInt(parts[1], 10, 64)
 	if err != nil {
@@ -593,6 +739,9 @@ func splitName(name string) (int, string, error) {
 
 func splitInstance(name string) (int, int, string, error) {
 	parts := strings.SplitN(name, "-", 4)
+	if len(parts) != 4 {
+		return 0, 0, "", errors.New("invalid format")
+	}
 
 	wi, err := strconv.ParseInt(parts[1], 10, 64)
 	if err != nil {
@@ -606,3 +755,18 @@ func splitInstance(name string) (int, int, string, error) {
 
 	return int(wi), int(oi), parts[3], nil
 }
+
+// staleEnv returns the age of the stale block output at the event time.
+func staleEnv(block ygs.I3BarBlock) []string {
+	v, ok := block.Custom[widgets.StaleSinceField]
+	if !ok {
+		return nil
+	}
+
+	since, err := strconv.ParseInt(string(v), 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	return []string{fmt.Sprintf("I3_STALE_SECONDS=%d", time.Now().Unix()-since)}
+}
@@ -0,0 +1,22 @@
+package ygs
+
+// ActionSpec describes an event action.
+type ActionSpec struct {
+	Name string
+	Func ActionFunc
+}
+
+// ActionFunc function to execute an action.
+type ActionFunc = func(ctx ActionContext, l Logger) error
+
+// ActionContext describes the event that triggered the action.
+type ActionContext struct {
+	// Args is the action string after the action name.
+	Args string
+	// Widget identifies the widget which received the event.
+	Widget WidgetInfo
+	// Event is the click event.
+	Event I3BarClickEvent
+	// Block is the clicked block.
+	Block I3BarBlock
+}
@@ -0,0 +1,46 @@
+package ygs
+
+import (
+	"encoding/json"
+)
+
+// I3BarBlocks is a list of blocks in the config.
+// It can be written as a JSON string or as a native YAML list.
+type I3BarBlocks []I3BarBlock
+
+// UnmarshalYAML unmarshals blocks from a JSON string or a YAML list.
+func (b *I3BarBlocks) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v interface{}
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+
+	data, err := BlocksJSONFromYAML(v)
+	if err != nil {
+		return err
+	}
+
+	var blocks []I3BarBlock
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		return err
+	}
+
+	*b = blocks
+
+	return nil
+}
+
+// MarshalYAML marshals blocks as a YAML list with json field names.
+func (b I3BarBlocks) MarshalYAML() (interface{}, error) {
+	data, err := json.Marshal([]I3BarBlock(b))
+	if err != nil {
+		return nil, err
+	}
+
+	var v []interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
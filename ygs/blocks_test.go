@@ -0,0 +1,58 @@
+package ygs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestI3BarBlocksUnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		in       string
+		fullText string
+	}{
+		{`blocks: [{full_text: "42"}]`, "42"},
+		{`blocks: [{full_text: 42}]`, "42"},
+		{`blocks: [{full_text: 1.5}]`, "1.5"},
+		{`blocks: [{full_text: true}]`, "true"},
+		{`blocks: '[{"full_text": "42"}]'`, "42"},
+	}
+
+	for _, tt := range tests {
+		var v struct {
+			Blocks I3BarBlocks
+		}
+
+		if err := yaml.Unmarshal([]byte(tt.in), &v); err != nil {
+			t.Errorf("%s: %s", tt.in, err)
+
+			continue
+		}
+
+		if len(v.Blocks) != 1 || v.Blocks[0].FullText != tt.fullText {
+			t.Errorf("%s: got %+v, want full_text %q", tt.in, v.Blocks, tt.fullText)
+		}
+	}
+}
+
+// The config variables pass converts numeric strings to int before the widget is decoded.
+func TestBlocksJSONFromYAMLNumbers(t *testing.T) {
+	v := []interface{}{
+		map[interface{}]interface{}{"full_text": 42, "min_width": 100, "separator_block_width": 5},
+	}
+
+	data, err := BlocksJSONFromYAML(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != `[{"full_text":"42","min_width":100,"separator_block_width":5}]` {
+		t.Errorf("got %s", data)
+	}
+
+	var blocks []I3BarBlock
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		t.Fatal(err)
+	}
+}
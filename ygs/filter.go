@@ -0,0 +1,45 @@
+package ygs
+
+// FilterSpec describes constructor for output filters.
+type FilterSpec struct {
+	Name          string
+	DefaultParams interface{}
+	NewFunc       NewFilterFunc
+}
+
+// NewFilterFunc function to create a new instance of a filter.
+type NewFilterFunc = func(params interface{}, l Logger) (Filter, error)
+
+// WidgetInfo identifies the widget in the config.
+type WidgetInfo struct {
+	// ID is the widget id.
+	ID string
+	// Name is the widget name.
+	Name string
+	// File is the config (or snippet) file where the widget is defined.
+	File string
+	// Index is the index of the widget in the file.
+	Index int
+}
+
+// Filter modifies output blocks.
+// The returned blocks replace the input blocks, return nil to drop all blocks.
+type Filter interface {
+	// Widget filters the widget blocks after templates are applied.
+	Widget(widget WidgetInfo, blocks []I3BarBlock) []I3BarBlock
+	// Frame filters all blocks before they are sent to i3bar (global filters only).
+	Frame(blocks []I3BarBlock) []I3BarBlock
+}
+
+// BlankFilter is a filters template, it passes blocks unchanged.
+type BlankFilter struct{}
+
+// Widget filters the widget blocks.
+func (f *BlankFilter) Widget(widget WidgetInfo, blocks []I3BarBlock) []I3BarBlock {
+	return blocks
+}
+
+// Frame filters all blocks.
+func (f *BlankFilter) Frame(blocks []I3BarBlock) []I3BarBlock {
+	return blocks
+}
@@ -177,3 +177,83 @@ func convertFieldValue(f reflect.Value, k string, v Vary, strict bool) error {
 
 	return nil
 }
+
+// JSONFromYAML converts a decoded YAML value to JSON.
+// Strings are returned as is, they are expected to contain JSON.
+func JSONFromYAML(v interface{}) ([]byte, error) {
+	if s, ok := v.(string); ok {
+		return []byte(s), nil
+	}
+
+	return json.Marshal(convertYAML(v))
+}
+
+// BlocksJSONFromYAML converts a decoded YAML block or list of blocks to JSON.
+// Numbers and booleans of string fields are converted to strings (full_text: 42).
+func BlocksJSONFromYAML(v interface{}) ([]byte, error) {
+	if s, ok := v.(string); ok {
+		return []byte(s), nil
+	}
+
+	v = convertYAML(v)
+
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		stringifyFields(vv)
+	case []interface{}:
+		for _, e := range vv {
+			if m, ok := e.(map[string]interface{}); ok {
+				stringifyFields(m)
+			}
+		}
+	}
+
+	return json.Marshal(v)
+}
+
+// stringifyFields converts scalar values of the block string fields to strings.
+func stringifyFields(m map[string]interface{}) {
+	t := reflect.TypeOf(I3BarBlock{})
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Type.Kind() != reflect.String {
+			continue
+		}
+
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+
+		switch v := m[name].(type) {
+		case bool, int, int64, uint64, float64:
+			m[name] = fmt.Sprint(v)
+		}
+	}
+}
+
+func convertYAML(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(vv))
+		for k, e := range vv {
+			m[fmt.Sprint(k)] = convertYAML(e)
+		}
+
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(vv))
+		for k, e := range vv {
+			m[k] = convertYAML(e)
+		}
+
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(vv))
+		for i, e := range vv {
+			l[i] = convertYAML(e)
+		}
+
+		return l
+	default:
+		return v
+	}
+}
@@ -17,10 +17,15 @@ type WidgetSpec struct {
 
 // WidgetSpec describes plugins initialization.
 type PluginSpec struct {
-	Name          string
-	DefaultParams interface{}
-	InitFunc      func(params interface{}, l Logger) error
-	ShutdownFunc  func() error
+	Name string
+	// APIVersion should be set to ygs.APIVersion,
+	// plugins built for another API version are not loaded.
+	APIVersion int
+	// MinHostVersion is the minimal YaGoStatus version required by the plugin (optional).
+	MinHostVersion string
+	DefaultParams  interface{}
+	InitFunc       func(params interface{}, l Logger) error
+	ShutdownFunc   func() error
 }
 
 // NewWidgetFunc function to create a new instance of a widget.
@@ -101,3 +106,76 @@ func RegisteredPlugins() []PluginSpec {
 
 	return plugins
 }
+
+// RegisterFilter registers output filter.
+func RegisterFilter(rf FilterSpec) error {
+	if rf.DefaultParams != nil {
+		def := reflect.ValueOf(rf.DefaultParams)
+		if def.Kind() != reflect.Struct {
+			return fmt.Errorf("defaultParams should be a struct")
+		}
+	}
+
+	if _, loaded := rs.LoadOrStore("filter_"+rf.Name, rf); loaded {
+		return fmt.Errorf("filter '%s' already registered", rf.Name)
+	}
+
+	return nil
+}
+
+// UnregisterFilter unregisters output filter.
+func UnregisterFilter(name string) bool {
+	_, ok := rs.LoadAndDelete("filter_" + name)
+
+	return ok
+}
+
+// RegisteredFilters returns list of registered output filters.
+func RegisteredFilters() []FilterSpec {
+	var filters []FilterSpec
+
+	rs.Range(func(k, v interface{}) bool {
+		if strings.HasPrefix(k.(string), "filter_") {
+			filters = append(filters, v.(FilterSpec))
+		}
+
+		return true
+	})
+
+	return filters
+}
+
+// RegisterAction registers event action.
+func RegisterAction(ra ActionSpec) error {
+	if ra.Func == nil {
+		return fmt.Errorf("action '%s': func is not set", ra.Name)
+	}
+
+	if _, loaded := rs.LoadOrStore("action_"+ra.Name, ra); loaded {
+		return fmt.Errorf("action '%s' already registered", ra.Name)
+	}
+
+	return nil
+}
+
+// UnregisterAction unregisters event action.
+func UnregisterAction(name string) bool {
+	_, ok := rs.LoadAndDelete("action_" + name)
+
+	return ok
+}
+
+// RegisteredActions returns list of registered event actions.
+func RegisteredActions() []ActionSpec {
+	var actions []ActionSpec
+
+	rs.Range(func(k, v interface{}) bool {
+		if strings.HasPrefix(k.(string), "action_") {
+			actions = append(actions, v.(ActionSpec))
+		}
+
+		return true
+	})
+
+	return actions
+}
@@ -0,0 +1,14 @@
+package ygs
+
+// Version contains YaGoStatus version.
+const Version = "1.1.0"
+
+// APIVersion is the version of the plugins API.
+//
+// It is incremented when the ygs package changes in a way that breaks plugins
+// built for the previous version: exported declarations are changed or removed,
+// methods are added to interfaces implemented by plugins, or fields are added to
+// structs exchanged with plugins. It is incremented at most once per release.
+//
+// Version 2: output filters, event actions and the Refresher interface.
+const APIVersion = 2
@@ -9,3 +9,8 @@ type Widget interface {
 	Continue() error
 	Shutdown() error
 }
+
+// Refresher is implemented by widgets that can be updated on demand (the refresh event action).
+type Refresher interface {
+	Refresh() error
+}